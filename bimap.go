@@ -0,0 +1,121 @@
+// Copyright 2023 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swiss
+
+// BiMap is a one-to-one mapping between keys and values,
+// indexed by a Map in each direction.
+type BiMap[K, V comparable] struct {
+	fwd *Map[K, V]
+	rev *Map[V, K]
+}
+
+// NewBiMap constructs a BiMap.
+func NewBiMap[K, V comparable](sz uint32) *BiMap[K, V] {
+	return &BiMap[K, V]{
+		fwd: NewMap[K, V](sz),
+		rev: NewMap[V, K](sz),
+	}
+}
+
+// Inverse returns a view of |b| with keys and values swapped.
+// The view shares storage with |b|.
+func (b *BiMap[K, V]) Inverse() *BiMap[V, K] {
+	return &BiMap[V, K]{fwd: b.rev, rev: b.fwd}
+}
+
+// Put attempts to map |key| to |value|, replacing any existing
+// value for |key|. Put fails and returns false if |value| is
+// already mapped to a different key.
+func (b *BiMap[K, V]) Put(key K, value V) (ok bool) {
+	if k, found := b.rev.Get(value); found {
+		return k == key
+	}
+	b.put(key, value)
+	return true
+}
+
+// ForcePut maps |key| to |value|, removing any existing
+// mapping for either |key| or |value|.
+func (b *BiMap[K, V]) ForcePut(key K, value V) {
+	if k, found := b.rev.Get(value); found {
+		if k == key {
+			return
+		}
+		b.fwd.Delete(k)
+		b.rev.Delete(value)
+	}
+	b.put(key, value)
+}
+
+func (b *BiMap[K, V]) put(key K, value V) {
+	v, found := b.fwd.upsert(key)
+	if found {
+		b.rev.Delete(*v)
+	}
+	*v = value
+	b.rev.Put(value, key)
+}
+
+// GetByKey returns the |value| mapped by |key| if one exists.
+func (b *BiMap[K, V]) GetByKey(key K) (value V, ok bool) {
+	return b.fwd.Get(key)
+}
+
+// GetByValue returns the |key| mapped to |value| if one exists.
+func (b *BiMap[K, V]) GetByValue(value V) (key K, ok bool) {
+	return b.rev.Get(value)
+}
+
+// HasKey returns true if |key| is present in |b|.
+func (b *BiMap[K, V]) HasKey(key K) bool {
+	return b.fwd.Has(key)
+}
+
+// HasValue returns true if |value| is present in |b|.
+func (b *BiMap[K, V]) HasValue(value V) bool {
+	return b.rev.Has(value)
+}
+
+// DeleteByKey attempts to remove |key| and its value, returns true successful.
+func (b *BiMap[K, V]) DeleteByKey(key K) (ok bool) {
+	var v V
+	if v, ok = b.fwd.Get(key); ok {
+		b.fwd.Delete(key)
+		b.rev.Delete(v)
+	}
+	return
+}
+
+// DeleteByValue attempts to remove |value| and its key, returns true successful.
+func (b *BiMap[K, V]) DeleteByValue(value V) (ok bool) {
+	return b.Inverse().DeleteByKey(value)
+}
+
+// Iter iterates the elements of the BiMap, passing them to the callback.
+// It makes the same guarantees as Map.Iter.
+func (b *BiMap[K, V]) Iter(cb func(k K, v V) (stop bool)) {
+	b.fwd.Iter(cb)
+}
+
+// Clear removes all elements from the BiMap.
+func (b *BiMap[K, V]) Clear() {
+	b.fwd.Clear()
+	b.rev.Clear()
+}
+
+// Count returns the number of elements in the BiMap.
+func (b *BiMap[K, V]) Count() int {
+	return b.fwd.Count()
+}
@@ -0,0 +1,132 @@
+// Copyright 2023 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swiss
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBiMap(t *testing.T) {
+	t.Run("put", func(t *testing.T) {
+		b := NewBiMap[string, int](0)
+		assert.True(t, b.Put("a", 1))
+		assert.True(t, b.Put("a", 1))
+		assert.False(t, b.Put("b", 1))
+		assert.True(t, b.Put("a", 2))
+		assert.False(t, b.HasValue(1))
+		assert.True(t, b.Put("b", 1))
+		checkBiMap(t, b, map[string]int{"a": 2, "b": 1})
+		b.ForcePut("c", 1)
+		checkBiMap(t, b, map[string]int{"a": 2, "c": 1})
+		b.ForcePut("a", 1)
+		checkBiMap(t, b, map[string]int{"a": 1})
+	})
+	t.Run("inverse", func(t *testing.T) {
+		b := NewBiMap[string, int](0)
+		inv := b.Inverse()
+		assert.True(t, inv.Put(1, "a"))
+		assert.True(t, inv.Put(2, "b"))
+		k, ok := b.GetByValue(1)
+		assert.True(t, ok)
+		assert.Equal(t, "a", k)
+		assert.True(t, b.DeleteByValue(2))
+		assert.False(t, inv.HasKey(2))
+		checkBiMap(t, b, map[string]int{"a": 1})
+		checkBiMap(t, inv, map[int]string{1: "a"})
+	})
+	t.Run("random ops", func(t *testing.T) {
+		// small domains force frequent conflicts
+		const domain = 64
+		b := NewBiMap[int, int](0)
+		fwd, rev := map[int]int{}, map[int]int{}
+		for i := 0; i < 100_000; i++ {
+			k, v := rand.Intn(domain), rand.Intn(domain)
+			switch rand.Intn(5) {
+			case 0:
+				k2, conflict := rev[v]
+				ok := b.Put(k, v)
+				assert.Equal(t, !conflict || k2 == k, ok)
+				if ok {
+					if v2, found := fwd[k]; found {
+						delete(rev, v2)
+					}
+					fwd[k], rev[v] = v, k
+				}
+			case 1:
+				if k2, ok := rev[v]; ok {
+					delete(fwd, k2)
+				}
+				if v2, ok := fwd[k]; ok {
+					delete(rev, v2)
+				}
+				b.ForcePut(k, v)
+				fwd[k], rev[v] = v, k
+			case 2:
+				v2, exp := fwd[k]
+				assert.Equal(t, exp, b.DeleteByKey(k))
+				if exp {
+					delete(rev, v2)
+					delete(fwd, k)
+				}
+			case 3:
+				k2, exp := rev[v]
+				assert.Equal(t, exp, b.DeleteByValue(v))
+				if exp {
+					delete(fwd, k2)
+					delete(rev, v)
+				}
+			case 4:
+				if rand.Intn(1000) == 0 {
+					b.Clear()
+					fwd, rev = map[int]int{}, map[int]int{}
+				}
+			}
+			if i%1000 == 0 {
+				checkBiMap(t, b, fwd)
+			}
+		}
+		checkBiMap(t, b, fwd)
+	})
+}
+
+// checkBiMap asserts the invariants of |b|: both directions
+// hold the same pairs, and those pairs are exactly |exp|.
+func checkBiMap[K, V comparable](t *testing.T, b *BiMap[K, V], exp map[K]V) {
+	require.Equal(t, len(exp), b.Count())
+	require.Equal(t, b.fwd.Count(), b.rev.Count())
+	b.fwd.Iter(func(k K, v V) (stop bool) {
+		k2, ok := b.rev.Get(v)
+		require.True(t, ok)
+		require.Equal(t, k, k2)
+		return
+	})
+	b.rev.Iter(func(v V, k K) (stop bool) {
+		v2, ok := b.fwd.Get(k)
+		require.True(t, ok)
+		require.Equal(t, v, v2)
+		return
+	})
+	for k, v := range exp {
+		act, ok := b.GetByKey(k)
+		require.True(t, ok)
+		require.Equal(t, v, act)
+		require.True(t, b.HasKey(k))
+		require.True(t, b.HasValue(v))
+	}
+}
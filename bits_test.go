@@ -15,6 +15,7 @@
 package swiss
 
 import (
+	"math"
 	"math/bits"
 	"math/rand"
 	"testing"
@@ -111,14 +112,41 @@ func TestFastMod(t *testing.T) {
 	t.Run("n=1000", func(t *testing.T) {
 		testFastMod(t, 1000)
 	})
+	t.Run("n=1<<32+1", func(t *testing.T) {
+		testFastMod(t, 1<<32+1)
+	})
+	t.Run("n=max", func(t *testing.T) {
+		testFastMod(t, math.MaxUint64)
+	})
+	t.Run("boundaries", func(t *testing.T) {
+		const max = math.MaxUint64
+		assert.Equal(t, uint64(0), fastModN(0, max))
+		assert.Equal(t, uint64(max-1), fastModN(max, max))
+		assert.Equal(t, uint64(0), fastModN(max, 1))
+		// beyond 2^32 groups
+		n := uint64(1<<32 + 7)
+		assert.Equal(t, uint64(0), fastModN(0, n))
+		assert.Equal(t, n-1, fastModN(max, n))
+		assert.Equal(t, n/2, fastModN(1<<63, n))
+		assert.Equal(t, uint64(1<<32), fastModN(1<<63, 1<<33))
+	})
+	t.Run("monotonic", func(t *testing.T) {
+		n := uint64(1<<40 + 3)
+		var prev uint64
+		for i := 0; i < 32*1024; i++ {
+			x := uint64(i) << 48
+			y := fastModN(x, n)
+			assert.GreaterOrEqual(t, y, prev)
+			prev = y
+		}
+	})
 }
 
-func testFastMod(t *testing.T, n uint32) {
+func testFastMod(t *testing.T, n uint64) {
 	const trials = 32 * 1024
 	for i := 0; i < trials; i++ {
-		x := rand.Uint32()
+		x := rand.Uint64()
 		y := fastModN(x, n)
 		assert.Less(t, y, n)
-		t.Logf("fastMod(%d, %d): %d", x, n, y)
 	}
 }
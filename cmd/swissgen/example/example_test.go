@@ -0,0 +1,398 @@
+// Copyright 2023 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package example
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// genMap is the method set shared by generated maps with int values.
+type genMap[K comparable] interface {
+	Has(key K) bool
+	Get(key K) (int, bool)
+	Put(key K, value int)
+	Delete(key K) bool
+	DeleteFunc(del func(k K, v int) bool) int
+	Iter(cb func(k K, v int) (stop bool))
+	Clear()
+	Count() int
+	Capacity() int
+}
+
+func newUint64Map(sz uint32) genMap[uint64] {
+	return NewUint64Map(sz)
+}
+
+func newStringMap(sz uint32) genMap[string] {
+	return NewStringMap(sz)
+}
+
+func TestGeneratedMap(t *testing.T) {
+	t.Run("strings=0", func(t *testing.T) {
+		testGeneratedMap(t, newStringMap, genStringData(16, 0))
+	})
+	t.Run("strings=100", func(t *testing.T) {
+		testGeneratedMap(t, newStringMap, genStringData(16, 100))
+	})
+	t.Run("strings=1000", func(t *testing.T) {
+		testGeneratedMap(t, newStringMap, genStringData(16, 1000))
+	})
+	t.Run("strings=10_000", func(t *testing.T) {
+		testGeneratedMap(t, newStringMap, genStringData(16, 10_000))
+	})
+	t.Run("strings=100_000", func(t *testing.T) {
+		testGeneratedMap(t, newStringMap, genStringData(16, 100_000))
+	})
+	t.Run("uint64=0", func(t *testing.T) {
+		testGeneratedMap(t, newUint64Map, genUint64Data(0))
+	})
+	t.Run("uint64=100", func(t *testing.T) {
+		testGeneratedMap(t, newUint64Map, genUint64Data(100))
+	})
+	t.Run("uint64=1000", func(t *testing.T) {
+		testGeneratedMap(t, newUint64Map, genUint64Data(1000))
+	})
+	t.Run("uint64=10_000", func(t *testing.T) {
+		testGeneratedMap(t, newUint64Map, genUint64Data(10_000))
+	})
+	t.Run("uint64=100_000", func(t *testing.T) {
+		testGeneratedMap(t, newUint64Map, genUint64Data(100_000))
+	})
+	t.Run("string capacity", func(t *testing.T) {
+		testGeneratedMapCapacity(t, newStringMap, func(n int) []string {
+			return genStringData(16, n)
+		})
+	})
+	t.Run("uint64 capacity", func(t *testing.T) {
+		testGeneratedMapCapacity(t, newUint64Map, genUint64Data)
+	})
+}
+
+func testGeneratedMap[K comparable](t *testing.T, fn func(uint32) genMap[K], keys []K) {
+	// sanity check
+	assert.Equal(t, len(keys), len(uniq(keys)), keys)
+	t.Run("put", func(t *testing.T) {
+		testMapPut(t, fn, keys)
+	})
+	t.Run("has", func(t *testing.T) {
+		testMapHas(t, fn, keys)
+	})
+	t.Run("get", func(t *testing.T) {
+		testMapGet(t, fn, keys)
+	})
+	t.Run("delete", func(t *testing.T) {
+		testMapDelete(t, fn, keys)
+	})
+	t.Run("delete func", func(t *testing.T) {
+		testMapDeleteFunc(t, fn, keys)
+	})
+	t.Run("clear", func(t *testing.T) {
+		testMapClear(t, fn, keys)
+	})
+	t.Run("iter", func(t *testing.T) {
+		testMapIter(t, fn, keys)
+	})
+	t.Run("grow", func(t *testing.T) {
+		testMapGrow(t, fn, keys)
+	})
+}
+
+func uniq[K comparable](keys []K) []K {
+	s := make(map[K]struct{}, len(keys))
+	for _, k := range keys {
+		s[k] = struct{}{}
+	}
+	u := make([]K, 0, len(keys))
+	for k := range s {
+		u = append(u, k)
+	}
+	return u
+}
+
+func genStringData(size, count int) (keys []string) {
+	src := rand.New(rand.NewSource(int64(size * count)))
+	letters := []rune("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ")
+	r := make([]rune, size*count)
+	for i := range r {
+		r[i] = letters[src.Intn(len(letters))]
+	}
+	keys = make([]string, count)
+	for i := range keys {
+		keys[i] = string(r[:size])
+		r = r[size:]
+	}
+	return
+}
+
+func genUint64Data(count int) (keys []uint64) {
+	keys = make([]uint64, count)
+	var x uint64
+	for i := range keys {
+		x += (rand.Uint64() % 128) + 1
+		keys[i] = x
+	}
+	return
+}
+
+func testMapPut[K comparable](t *testing.T, fn func(uint32) genMap[K], keys []K) {
+	m := fn(uint32(len(keys)))
+	assert.Equal(t, 0, m.Count())
+	for i, key := range keys {
+		m.Put(key, i)
+	}
+	assert.Equal(t, len(keys), m.Count())
+	// overwrite
+	for i, key := range keys {
+		m.Put(key, -i)
+	}
+	assert.Equal(t, len(keys), m.Count())
+	for i, key := range keys {
+		act, ok := m.Get(key)
+		assert.True(t, ok)
+		assert.Equal(t, -i, act)
+	}
+}
+
+func testMapHas[K comparable](t *testing.T, fn func(uint32) genMap[K], keys []K) {
+	m := fn(uint32(len(keys)))
+	for i, key := range keys {
+		m.Put(key, i)
+	}
+	for _, key := range keys {
+		ok := m.Has(key)
+		assert.True(t, ok)
+	}
+}
+
+func testMapGet[K comparable](t *testing.T, fn func(uint32) genMap[K], keys []K) {
+	m := fn(uint32(len(keys)))
+	for i, key := range keys {
+		m.Put(key, i)
+	}
+	for i, key := range keys {
+		act, ok := m.Get(key)
+		assert.True(t, ok)
+		assert.Equal(t, i, act)
+	}
+}
+
+func testMapDelete[K comparable](t *testing.T, fn func(uint32) genMap[K], keys []K) {
+	m := fn(uint32(len(keys)))
+	assert.Equal(t, 0, m.Count())
+	for i, key := range keys {
+		m.Put(key, i)
+	}
+	assert.Equal(t, len(keys), m.Count())
+	for _, key := range keys {
+		assert.True(t, m.Delete(key))
+		ok := m.Has(key)
+		assert.False(t, ok)
+		assert.False(t, m.Delete(key))
+	}
+	assert.Equal(t, 0, m.Count())
+	// put keys back after deleting them
+	for i, key := range keys {
+		m.Put(key, i)
+	}
+	assert.Equal(t, len(keys), m.Count())
+}
+
+func testMapDeleteFunc[K comparable](t *testing.T, fn func(uint32) genMap[K], keys []K) {
+	m := fn(uint32(len(keys)))
+	for i, key := range keys {
+		m.Put(key, i)
+	}
+	n := m.DeleteFunc(func(k K, v int) bool {
+		return v%2 == 0
+	})
+	assert.Equal(t, (len(keys)+1)/2, n)
+	assert.Equal(t, len(keys)-n, m.Count())
+	for i, key := range keys {
+		act, ok := m.Get(key)
+		if i%2 == 0 {
+			assert.False(t, ok)
+		} else {
+			assert.True(t, ok)
+			assert.Equal(t, i, act)
+		}
+	}
+	// delete everything that remains
+	n = m.DeleteFunc(func(k K, v int) bool {
+		return true
+	})
+	assert.Equal(t, len(keys)/2, n)
+	assert.Equal(t, 0, m.Count())
+	for _, key := range keys {
+		assert.False(t, m.Has(key))
+	}
+	// put keys back after deleting them
+	for i, key := range keys {
+		m.Put(key, i)
+	}
+	assert.Equal(t, len(keys), m.Count())
+}
+
+func testMapClear[K comparable](t *testing.T, fn func(uint32) genMap[K], keys []K) {
+	m := fn(0)
+	assert.Equal(t, 0, m.Count())
+	for i, key := range keys {
+		m.Put(key, i)
+	}
+	assert.Equal(t, len(keys), m.Count())
+	m.Clear()
+	assert.Equal(t, 0, m.Count())
+	for _, key := range keys {
+		ok := m.Has(key)
+		assert.False(t, ok)
+		_, ok = m.Get(key)
+		assert.False(t, ok)
+	}
+	var calls int
+	m.Iter(func(k K, v int) (stop bool) {
+		calls++
+		return
+	})
+	assert.Equal(t, 0, calls)
+	m.Clear()
+	assert.Equal(t, 0, m.Count())
+}
+
+func testMapIter[K comparable](t *testing.T, fn func(uint32) genMap[K], keys []K) {
+	m := fn(uint32(len(keys)))
+	for i, key := range keys {
+		m.Put(key, i)
+	}
+	visited := make(map[K]uint, len(keys))
+	m.Iter(func(k K, v int) (stop bool) {
+		visited[k] = 0
+		stop = true
+		return
+	})
+	if len(keys) == 0 {
+		assert.Equal(t, len(visited), 0)
+	} else {
+		assert.Equal(t, len(visited), 1)
+	}
+	for _, k := range keys {
+		visited[k] = 0
+	}
+	m.Iter(func(k K, v int) (stop bool) {
+		visited[k]++
+		return
+	})
+	for _, c := range visited {
+		assert.Equal(t, c, uint(1))
+	}
+	// mutate on iter
+	m.Iter(func(k K, v int) (stop bool) {
+		m.Put(k, -v)
+		return
+	})
+	for i, key := range keys {
+		act, ok := m.Get(key)
+		assert.True(t, ok)
+		assert.Equal(t, -i, act)
+	}
+}
+
+func testMapGrow[K comparable](t *testing.T, fn func(uint32) genMap[K], keys []K) {
+	n := uint32(len(keys))
+	m := fn(n / 10)
+	for i, key := range keys {
+		m.Put(key, i)
+	}
+	for i, key := range keys {
+		act, ok := m.Get(key)
+		assert.True(t, ok)
+		assert.Equal(t, i, act)
+	}
+}
+
+func testGeneratedMapCapacity[K comparable](t *testing.T, fn func(uint32) genMap[K], gen func(n int) []K) {
+	const load = uint64MapMaxAvgGroupLoad
+	caps := []uint32{
+		1 * load,
+		2 * load,
+		3 * load,
+		4 * load,
+		5 * load,
+		10 * load,
+		25 * load,
+		50 * load,
+		100 * load,
+	}
+	for _, c := range caps {
+		m := fn(c)
+		assert.Equal(t, int(c), m.Capacity())
+		keys := gen(rand.Intn(int(c)))
+		for i, k := range keys {
+			m.Put(k, i)
+		}
+		assert.Equal(t, int(c)-len(keys), m.Capacity())
+		assert.Equal(t, int(c), m.Count()+m.Capacity())
+	}
+}
+
+func TestGeneratedMapReseed(t *testing.T) {
+	m := NewUint64Map(0)
+	for i := uint64(0); i < 10_000; i++ {
+		m.Put(i, int(i))
+	}
+	// force a reseed, elements survive the new seed
+	seed := m.seed
+	m.reseed()
+	assert.NotEqual(t, seed, m.seed)
+	assert.True(t, m.reseeded)
+	for i := uint64(0); i < 10_000; i++ {
+		v, ok := m.Get(i)
+		assert.True(t, ok)
+		assert.Equal(t, int(i), v)
+	}
+	// at most once per size
+	seed = m.seed
+	m.reseed()
+	assert.Equal(t, seed, m.seed)
+}
+
+func BenchmarkGeneratedMap(b *testing.B) {
+	keys := genUint64Data(1000)
+	b.Run("Uint64Map", func(b *testing.B) {
+		m := NewUint64Map(uint32(len(keys)))
+		for i, k := range keys {
+			m.Put(k, i)
+		}
+		b.ResetTimer()
+		var ok bool
+		for i := 0; i < b.N; i++ {
+			_, ok = m.Get(keys[uint32(i)%uint32(len(keys))])
+		}
+		assert.True(b, ok)
+	})
+	b.Run("runtime map", func(b *testing.B) {
+		m := make(map[uint64]int, len(keys))
+		for i, k := range keys {
+			m[k] = i
+		}
+		b.ResetTimer()
+		var ok bool
+		for i := 0; i < b.N; i++ {
+			_, ok = m[keys[uint32(i)%uint32(len(keys))]]
+		}
+		assert.True(b, ok)
+	})
+}
@@ -0,0 +1,596 @@
+// Copyright 2023 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"strings"
+	"text/template"
+	"unicode"
+	"unicode/utf8"
+)
+
+// config describes a generated map.
+type config struct {
+	// Type is the name of the map type
+	Type string
+	// Key is the key type, an integer type or string
+	Key string
+	// Value is the value type
+	Value string
+	// Package is the package of the generated file
+	Package string
+}
+
+// keyKinds maps supported key types to the hash function emitted for them.
+var keyKinds = map[string]string{
+	"int":     "int",
+	"int8":    "int",
+	"int16":   "int",
+	"int32":   "int",
+	"int64":   "int",
+	"uint":    "int",
+	"uint8":   "int",
+	"uint16":  "int",
+	"uint32":  "int",
+	"uint64":  "int",
+	"uintptr": "int",
+	"byte":    "int",
+	"rune":    "int",
+	"string":  "string",
+}
+
+// generate returns the formatted source of the map described by |c|.
+func generate(c config) ([]byte, error) {
+	if !token.IsIdentifier(c.Type) {
+		return nil, fmt.Errorf("invalid type name %q", c.Type)
+	}
+	if !token.IsIdentifier(c.Package) {
+		return nil, fmt.Errorf("invalid package name %q", c.Package)
+	}
+	kind, ok := keyKinds[c.Key]
+	if !ok {
+		return nil, fmt.Errorf("unsupported key type %q, want an integer type or string", c.Key)
+	}
+	if c.Value == "" {
+		return nil, fmt.Errorf("missing value type")
+	}
+	prefix := prefixOf(c.Type)
+	// the generated code must not shadow the
+	// types it refers to, nor redeclare them
+	if reserved.locals[c.Type] {
+		return nil, fmt.Errorf("type name %q collides with an identifier in the generated code", c.Type)
+	}
+	value, err := parser.ParseExpr(c.Value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid value type %q: %w", c.Value, err)
+	}
+	for _, name := range identNames(value) {
+		if types.Universe.Lookup(name) != nil {
+			continue
+		}
+		declared := name == "New"+c.Type || (strings.HasPrefix(name, prefix) && reserved.decls[name[len(prefix):]])
+		if reserved.locals[name] || declared {
+			return nil, fmt.Errorf("value type %q refers to %s, which collides with an identifier in the generated code", c.Value, name)
+		}
+	}
+	return execute(c, prefix, kind)
+}
+
+// execute fills in and formats the map template.
+func execute(c config, prefix, kind string) ([]byte, error) {
+	var buf bytes.Buffer
+	err := mapTemplate.Execute(&buf, struct {
+		config
+		Prefix  string
+		KeyKind string
+	}{c, prefix, kind})
+	if err != nil {
+		return nil, err
+	}
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("formatting generated map: %w", err)
+	}
+	return src, nil
+}
+
+// prefixOf returns the prefix of the unexported declarations of map
+// type |typ|, the lower cased type name, so that maps can share a
+// package.
+func prefixOf(typ string) string {
+	r, n := utf8.DecodeRuneInString(typ)
+	return string(unicode.ToLower(r)) + typ[n:]
+}
+
+// reserved holds the identifiers that the map template refers to.
+var reserved = templateNames()
+
+// templateNames collects the identifiers that the map template refers
+// to, by parsing a map generated for a placeholder type. |locals| holds
+// the names of locals, imports and predeclared identifiers. |decls|
+// holds the names of package level declarations, less their prefix.
+func templateNames() (names struct{ locals, decls map[string]bool }) {
+	names.locals, names.decls = make(map[string]bool), make(map[string]bool)
+	const typ = "SwissgenPlaceholder"
+	prefix := prefixOf(typ)
+	for key := range keyKinds {
+		src, err := execute(config{Type: typ, Key: key, Value: "int", Package: "p"}, prefix, keyKinds[key])
+		if err != nil {
+			panic(err)
+		}
+		f, err := parser.ParseFile(token.NewFileSet(), "", src, 0)
+		if err != nil {
+			panic(err)
+		}
+		for _, name := range identNames(f) {
+			switch {
+			case name == typ || name == "New"+typ:
+			case strings.HasPrefix(name, prefix):
+				names.decls[name[len(prefix):]] = true
+			default:
+				names.locals[name] = true
+			}
+		}
+	}
+	return
+}
+
+// identNames returns the names of the identifiers in |n| that are
+// resolved in scope, skipping package, method, field and selected names.
+func identNames(n ast.Node) (names []string) {
+	skip := make(map[*ast.Ident]bool)
+	ast.Inspect(n, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.File:
+			skip[n.Name] = true
+		case *ast.FuncDecl:
+			if n.Recv != nil {
+				skip[n.Name] = true
+			}
+		case *ast.StructType:
+			for _, f := range n.Fields.List {
+				for _, id := range f.Names {
+					skip[id] = true
+				}
+			}
+		case *ast.SelectorExpr:
+			skip[n.Sel] = true
+		case *ast.KeyValueExpr:
+			if id, ok := n.Key.(*ast.Ident); ok {
+				skip[id] = true
+			}
+		case *ast.Ident:
+			if !skip[n] {
+				names = append(names, n.Name)
+			}
+		}
+		return true
+	})
+	return
+}
+
+var mapTemplate = template.Must(template.New("map").Parse(`// Code generated by swissgen -type {{.Type}} -key {{.Key}} -value {{.Value}}. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"hash/maphash"
+	"math/bits"
+)
+
+// {{.Type}} is an open-addressing hash map of {{.Key}} keys
+// to {{.Value}} values based on Abseil's flat_hash_map.
+type {{.Type}} struct {
+	ctrl     []uint64
+	groups   []{{.Prefix}}Group
+	seed     uint64
+	resident uint64
+	dead     uint64
+	limit    uint64
+	// reseeded is set if the table was reseeded at its current size
+	reseeded bool
+}
+
+// {{.Prefix}}Group is a group of 8 key-value pairs, whose
+// metadata bytes are packed in a uint64 of the ctrl array.
+type {{.Prefix}}Group struct {
+	keys   [{{.Prefix}}GroupSize]{{.Key}}
+	values [{{.Prefix}}GroupSize]{{.Value}}
+}
+
+const (
+	{{.Prefix}}GroupSize       = 8
+	{{.Prefix}}MaxAvgGroupLoad = 7
+
+	{{.Prefix}}LoBits uint64 = 0x0101010101010101
+	{{.Prefix}}HiBits uint64 = 0x8080808080808080
+
+	{{.Prefix}}Empty     uint64 = 0x80
+	{{.Prefix}}Tombstone uint64 = 0xfe
+)
+
+// New{{.Type}} constructs a {{.Type}} that can hold
+// |sz| elements before it first grows.
+func New{{.Type}}(sz uint32) (m *{{.Type}}) {
+	m = &{{.Type}}{seed: {{.Prefix}}NewSeed()}
+	m.alloc({{.Prefix}}NumGroups(uint64(sz)))
+	return
+}
+
+// Has returns true if |key| is present in |m|.
+func (m *{{.Type}}) Has(key {{.Key}}) (ok bool) {
+	_, _, ok = m.find(key)
+	return
+}
+
+// Get returns the |value| mapped by |key| if one exists.
+func (m *{{.Type}}) Get(key {{.Key}}) (value {{.Value}}, ok bool) {
+	var g uint64
+	var s uint32
+	if g, s, ok = m.find(key); ok {
+		value = m.groups[g].values[s]
+	}
+	return
+}
+
+// Put attempts to insert |key| and |value|. If inserting requires an
+// abnormally long probe sequence, as happens when keys are chosen to
+// collide, the map is rebuilt with a new hash seed.
+func (m *{{.Type}}) Put(key {{.Key}}, value {{.Value}}) {
+	if m.resident >= m.limit {
+		m.rehash(m.nextSize())
+	}
+	hi, lo := {{.Prefix}}SplitHash({{.Prefix}}Hash(key, m.seed))
+	g := {{.Prefix}}ProbeStart(hi, len(m.groups))
+	var probes uint64
+	for {
+		matches := {{.Prefix}}MatchH2(m.ctrl[g], lo)
+		for matches != 0 {
+			s := {{.Prefix}}NextMatch(&matches)
+			if key == m.groups[g].keys[s] { // update
+				m.groups[g].values[s] = value
+				return
+			}
+		}
+		// |key| is not in group |g|,
+		// stop probing if we see an empty slot
+		matches = {{.Prefix}}MatchEmpty(m.ctrl[g])
+		if matches != 0 { // insert
+			s := {{.Prefix}}NextMatch(&matches)
+			m.groups[g].keys[s] = key
+			m.groups[g].values[s] = value
+			m.setCtrl(g, s, lo)
+			m.resident++
+			if probes > 8*uint64(bits.Len64(uint64(len(m.groups)))) {
+				m.reseed()
+			}
+			return
+		}
+		probes++
+		g += 1 // linear probing
+		if g >= uint64(len(m.groups)) {
+			g = 0
+		}
+	}
+}
+
+// Delete attempts to remove |key|, returns true successful.
+func (m *{{.Type}}) Delete(key {{.Key}}) (ok bool) {
+	var g uint64
+	var s uint32
+	if g, s, ok = m.find(key); ok {
+		m.deleteAt(g, s)
+	}
+	return
+}
+
+// DeleteFunc removes every element for which |del| returns true
+// in a single pass over the table, and returns the number of
+// elements removed.
+func (m *{{.Type}}) DeleteFunc(del func(k {{.Key}}, v {{.Value}}) bool) (n int) {
+	for g := range m.ctrl {
+		for s := uint32(0); s < {{.Prefix}}GroupSize; s++ {
+			if !m.full(uint64(g), s) {
+				continue
+			}
+			if del(m.groups[g].keys[s], m.groups[g].values[s]) {
+				m.deleteAt(uint64(g), s)
+				n++
+			}
+		}
+	}
+	// bulk deletes can leave many tombstones
+	// behind, compact the table in place
+	if m.dead > 0 && m.dead >= m.resident/2 {
+		m.rehash(uint64(len(m.groups)))
+	}
+	return
+}
+
+// Iter iterates the elements of the map, passing them to the callback.
+// It guarantees that any key in the map will be visited only once, and
+// for un-mutated maps, every key will be visited once. If the map is
+// Mutated during iteration, mutations will be reflected on return from
+// Iter, but the set of keys visited by Iter is non-deterministic.
+func (m *{{.Type}}) Iter(cb func(k {{.Key}}, v {{.Value}}) (stop bool)) {
+	// take a consistent view of the table in case
+	// we rehash during iteration
+	ctrl, groups := m.ctrl, m.groups
+	// pick a random starting group
+	g := {{.Prefix}}ProbeStart({{.Prefix}}H1({{.Prefix}}NewSeed()), len(groups))
+	for n := 0; n < len(groups); n++ {
+		for s := uint32(0); s < {{.Prefix}}GroupSize; s++ {
+			c := ctrl[g] >> (8 * s) & 0xff
+			if c == {{.Prefix}}Empty || c == {{.Prefix}}Tombstone {
+				continue
+			}
+			k, v := groups[g].keys[s], groups[g].values[s]
+			if stop := cb(k, v); stop {
+				return
+			}
+		}
+		g++
+		if g >= uint64(len(groups)) {
+			g = 0
+		}
+	}
+}
+
+// Clear removes all elements from the map.
+func (m *{{.Type}}) Clear() {
+	if m.resident == 0 {
+		return
+	}
+	for i := range m.ctrl {
+		if m.ctrl[i] == {{.Prefix}}HiBits {
+			continue
+		}
+		m.ctrl[i] = {{.Prefix}}HiBits
+		m.groups[i] = {{.Prefix}}Group{}
+	}
+	m.resident, m.dead = 0, 0
+}
+
+// Count returns the number of elements in the map.
+func (m *{{.Type}}) Count() int {
+	return int(m.resident - m.dead)
+}
+
+// Capacity returns the number of additional elements
+// the can be added to the map before resizing.
+func (m *{{.Type}}) Capacity() int {
+	return int(m.limit - m.resident)
+}
+
+// find returns the location of |key| if present, or its insertion location if absent.
+func (m *{{.Type}}) find(key {{.Key}}) (g uint64, s uint32, ok bool) {
+	hi, lo := {{.Prefix}}SplitHash({{.Prefix}}Hash(key, m.seed))
+	g = {{.Prefix}}ProbeStart(hi, len(m.groups))
+	for {
+		matches := {{.Prefix}}MatchH2(m.ctrl[g], lo)
+		for matches != 0 {
+			s = {{.Prefix}}NextMatch(&matches)
+			if key == m.groups[g].keys[s] {
+				return g, s, true
+			}
+		}
+		// |key| is not in group |g|,
+		// stop probing if we see an empty slot
+		matches = {{.Prefix}}MatchEmpty(m.ctrl[g])
+		if matches != 0 {
+			s = {{.Prefix}}NextMatch(&matches)
+			return g, s, false
+		}
+		g += 1 // linear probing
+		if g >= uint64(len(m.groups)) {
+			g = 0
+		}
+	}
+}
+
+// deleteAt removes the element in slot |s| of group |g|.
+func (m *{{.Type}}) deleteAt(g uint64, s uint32) {
+	// if group |g| has an empty slot, probes into |g| already
+	// stop there and slot |s| can be reclaimed immediately
+	if {{.Prefix}}MatchEmpty(m.ctrl[g]) != 0 {
+		m.setCtrl(g, s, {{.Prefix}}Empty)
+		m.resident--
+	} else {
+		m.setCtrl(g, s, {{.Prefix}}Tombstone)
+		m.dead++
+	}
+	var k {{.Key}}
+	var v {{.Value}}
+	m.groups[g].keys[s] = k
+	m.groups[g].values[s] = v
+}
+
+func (m *{{.Type}}) full(g uint64, s uint32) bool {
+	c := m.ctrl[g] >> (8 * s) & 0xff
+	return c != {{.Prefix}}Empty && c != {{.Prefix}}Tombstone
+}
+
+func (m *{{.Type}}) setCtrl(g uint64, s uint32, c uint64) {
+	m.ctrl[g] = m.ctrl[g]&^(0xff<<(8*s)) | c<<(8*s)
+}
+
+// nextSize returns the number of groups to rehash a full table
+// into, compacting it in place if half its slots are tombstones.
+func (m *{{.Type}}) nextSize() (n uint64) {
+	n = uint64(len(m.groups)) * 2
+	if m.dead > 0 && m.dead >= m.resident/2 {
+		n = uint64(len(m.groups))
+	}
+	// leave room for the element being inserted
+	if min := {{.Prefix}}NumGroups(m.resident - m.dead + 1); n < min {
+		n = min
+	}
+	return
+}
+
+// reseed rebuilds the table with a new hash seed, at most once per size.
+func (m *{{.Type}}) reseed() {
+	if m.reseeded {
+		return
+	}
+	m.seed = {{.Prefix}}NewSeed()
+	m.rehash(uint64(len(m.groups)))
+	m.reseeded = true
+}
+
+// rehash moves all elements into a new table of |n| groups.
+func (m *{{.Type}}) rehash(n uint64) {
+	if n != uint64(len(m.groups)) {
+		m.reseeded = false
+	}
+	groups, ctrl := m.groups, m.ctrl
+	m.alloc(n)
+	for g := range ctrl {
+		for s := uint32(0); s < {{.Prefix}}GroupSize; s++ {
+			c := ctrl[g] >> (8 * s) & 0xff
+			if c == {{.Prefix}}Empty || c == {{.Prefix}}Tombstone {
+				continue
+			}
+			m.insert(groups[g].keys[s], groups[g].values[s])
+		}
+	}
+}
+
+// insert places |key| and |value| in the first free slot of their
+// probe sequence. |key| must be absent, and |m| must not be full.
+func (m *{{.Type}}) insert(key {{.Key}}, value {{.Value}}) {
+	hi, lo := {{.Prefix}}SplitHash({{.Prefix}}Hash(key, m.seed))
+	g := {{.Prefix}}ProbeStart(hi, len(m.groups))
+	for {
+		matches := {{.Prefix}}MatchEmpty(m.ctrl[g])
+		if matches != 0 {
+			s := {{.Prefix}}NextMatch(&matches)
+			m.groups[g].keys[s] = key
+			m.groups[g].values[s] = value
+			m.setCtrl(g, s, lo)
+			m.resident++
+			return
+		}
+		g += 1 // linear probing
+		if g >= uint64(len(m.groups)) {
+			g = 0
+		}
+	}
+}
+
+// alloc replaces the table with an empty table of |n| groups.
+func (m *{{.Type}}) alloc(n uint64) {
+	m.ctrl = make([]uint64, n)
+	m.groups = make([]{{.Prefix}}Group, n)
+	for i := range m.ctrl {
+		m.ctrl[i] = {{.Prefix}}HiBits
+	}
+	m.limit = n * {{.Prefix}}MaxAvgGroupLoad
+	m.resident, m.dead = 0, 0
+}
+
+// {{.Prefix}}NumGroups returns the minimum number
+// of groups needed to store |n| elems.
+func {{.Prefix}}NumGroups(n uint64) (groups uint64) {
+	groups = n / {{.Prefix}}MaxAvgGroupLoad
+	if n%{{.Prefix}}MaxAvgGroupLoad != 0 || n == 0 {
+		groups++
+	}
+	return
+}
+
+// {{.Prefix}}SplitHash returns the 57 bit prefix and 7 bit suffix of |h|.
+func {{.Prefix}}SplitHash(h uint64) (hi, lo uint64) {
+	return {{.Prefix}}H1(h), h & 0x7f
+}
+
+func {{.Prefix}}H1(h uint64) uint64 {
+	return (h & 0xffff_ffff_ffff_ff80) >> 7
+}
+
+// {{.Prefix}}ProbeStart maps |hi| to a group.
+func {{.Prefix}}ProbeStart(hi uint64, groups int) uint64 {
+	g, _ := bits.Mul64(hi<<7, uint64(groups))
+	return g
+}
+
+func {{.Prefix}}MatchH2(c, lo uint64) uint64 {
+	return {{.Prefix}}HasZeroByte(c ^ ({{.Prefix}}LoBits * lo))
+}
+
+func {{.Prefix}}MatchEmpty(c uint64) uint64 {
+	return {{.Prefix}}HasZeroByte(c ^ {{.Prefix}}HiBits)
+}
+
+// https://graphics.stanford.edu/~seander/bithacks.html##ValueInWord
+func {{.Prefix}}HasZeroByte(x uint64) uint64 {
+	return ((x - {{.Prefix}}LoBits) & ^(x)) & {{.Prefix}}HiBits
+}
+
+func {{.Prefix}}NextMatch(b *uint64) uint32 {
+	s := uint32(bits.TrailingZeros64(*b))
+	*b &= *b - 1 // clear the lowest set bit
+	return s >> 3
+}
+
+func {{.Prefix}}NewSeed() uint64 {
+	// a zero Hash is randomly seeded
+	return new(maphash.Hash).Sum64()
+}
+{{if eq .KeyKind "int"}}
+func {{.Prefix}}Hash(key {{.Key}}, seed uint64) uint64 {
+	const (
+		p0 = 0xa0761d6478bd642f
+		p1 = 0xe7037ed1a0b428db
+		p2 = 0x8ebc6af09c88c6e3
+	)
+	h := {{.Prefix}}MulMix(uint64(key)^p1, seed^p0^p2)
+	return {{.Prefix}}MulMix(h^p2, 8^p1)
+}
+{{else}}
+func {{.Prefix}}Hash(key string, seed uint64) uint64 {
+	const (
+		p0 = 0xa0761d6478bd642f
+		p1 = 0xe7037ed1a0b428db
+		p2 = 0x8ebc6af09c88c6e3
+	)
+	h, n := seed^p0, len(key)
+	for ; len(key) >= 8; key = key[8:] {
+		w := uint64(key[0]) | uint64(key[1])<<8 | uint64(key[2])<<16 | uint64(key[3])<<24 |
+			uint64(key[4])<<32 | uint64(key[5])<<40 | uint64(key[6])<<48 | uint64(key[7])<<56
+		h = {{.Prefix}}MulMix(w^p1, h^p2)
+	}
+	if len(key) > 0 {
+		var w uint64
+		for i := len(key) - 1; i >= 0; i-- {
+			w = w<<8 | uint64(key[i])
+		}
+		h = {{.Prefix}}MulMix(w^p1, h^p2)
+	}
+	return {{.Prefix}}MulMix(h^p2, uint64(n)^p1)
+}
+{{end}}
+func {{.Prefix}}MulMix(a, b uint64) uint64 {
+	hi, lo := bits.Mul64(a, b)
+	return hi ^ lo
+}
+`))
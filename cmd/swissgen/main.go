@@ -0,0 +1,61 @@
+// Copyright 2023 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Command swissgen generates a hash map for a concrete key and value
+// type. The generated map uses the same table as swiss.Map, but hashes
+// and compares keys without going through generic dictionaries, so
+// that its hash function and key comparisons can be inlined.
+//
+// Usage:
+//
+//	//go:generate swissgen -type Uint64Map -key uint64 -value int
+//
+// Keys must be an integer type or string. Values may be any
+// type that is in scope in the generated package. Names used by
+// the generated code, such as its locals, are rejected as the map
+// type or in the value type.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+func main() {
+	var c config
+	flag.StringVar(&c.Type, "type", "", "name of the generated map type")
+	flag.StringVar(&c.Key, "key", "", "key type, an integer type or string")
+	flag.StringVar(&c.Value, "value", "", "value type")
+	flag.StringVar(&c.Package, "pkg", os.Getenv("GOPACKAGE"), "package of the generated file")
+	out := flag.String("o", "", "output file, defaults to <type>.go")
+	flag.Parse()
+	if c.Package == "" {
+		c.Package = "main"
+	}
+	if *out == "" {
+		*out = strings.ToLower(c.Type) + ".go"
+	}
+
+	src, err := generate(c)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "swissgen:", err)
+		os.Exit(2)
+	}
+	if err = os.WriteFile(*out, src, 0644); err != nil {
+		fmt.Fprintln(os.Stderr, "swissgen:", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,130 @@
+// Copyright 2023 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerate(t *testing.T) {
+	// the example package must be regenerated
+	// with go generate when the template changes
+	golden := []struct {
+		file string
+		c    config
+	}{
+		{"uint64map.go", config{Type: "Uint64Map", Key: "uint64", Value: "int", Package: "example"}},
+		{"stringmap.go", config{Type: "StringMap", Key: "string", Value: "int", Package: "example"}},
+	}
+	for _, g := range golden {
+		t.Run(g.file, func(t *testing.T) {
+			exp, err := os.ReadFile(filepath.Join("example", g.file))
+			require.NoError(t, err)
+			act, err := generate(g.c)
+			require.NoError(t, err)
+			assert.Equal(t, string(exp), string(act))
+		})
+	}
+
+	t.Run("key types", func(t *testing.T) {
+		for key := range keyKinds {
+			c := config{Type: "M", Key: key, Value: "[]byte", Package: "p"}
+			src, err := generate(c)
+			require.NoError(t, err, key)
+			typeCheck(t, c, src)
+		}
+	})
+	t.Run("names", func(t *testing.T) {
+		// names used by the template must be rejected,
+		// any other name must compile
+		names := []string{"M", "m", "x", "g", "s", "key", "value", "ctrl", "groups",
+			"seed", "bits", "maphash", "uint64", "len", "Group", "mGroup", "find", "Has", "_"}
+		for _, name := range names {
+			for _, key := range []string{"uint32", "string"} {
+				c := config{Type: name, Key: key, Value: "int", Package: "p"}
+				src, err := generate(c)
+				if err == nil {
+					typeCheck(t, c, src)
+				}
+			}
+		}
+		for _, name := range []string{"m", "g", "key", "bits", "uint64"} {
+			_, err := generate(config{Type: name, Key: "int", Value: "int", Package: "p"})
+			assert.Error(t, err, name)
+		}
+		// value types are declared in the package
+		values := []string{"int", "*Item", "[]Item", "map[Item]v", "struct{ s Item }", "func(k int) Item",
+			"v", "s", "Item", "[n]int", "mGroup", "mHash", "NewM"}
+		for _, value := range values {
+			c := config{Type: "M", Key: "int", Value: value, Package: "p"}
+			src, err := generate(c)
+			if err == nil {
+				typeCheck(t, c, src)
+			}
+		}
+		for _, value := range []string{"v", "[]s", "map[int]v", "mGroup", "[n]int"} {
+			_, err := generate(config{Type: "M", Key: "int", Value: value, Package: "p"})
+			assert.Error(t, err, value)
+		}
+	})
+	t.Run("invalid", func(t *testing.T) {
+		_, err := generate(config{Type: "M", Key: "float64", Value: "int", Package: "p"})
+		assert.Error(t, err)
+		_, err = generate(config{Type: "M", Key: "[4]byte", Value: "int", Package: "p"})
+		assert.Error(t, err)
+		_, err = generate(config{Type: "", Key: "int", Value: "int", Package: "p"})
+		assert.Error(t, err)
+		_, err = generate(config{Type: "M", Key: "int", Value: "", Package: "p"})
+		assert.Error(t, err)
+		_, err = generate(config{Type: "M", Key: "int", Value: "int", Package: "p-q"})
+		assert.Error(t, err)
+		// value types must parse
+		_, err = generate(config{Type: "M", Key: "int", Value: "map[", Package: "p"})
+		assert.Error(t, err)
+	})
+}
+
+// srcImporter type checks imports from source, caching the packages
+// it imports.
+var srcImporter = importer.ForCompiler(token.NewFileSet(), "source", nil)
+
+// typeCheck checks that |src| compiles in package |c.Package|, along
+// with declarations of the value types used by the tests.
+func typeCheck(t *testing.T, c config, src []byte) {
+	t.Helper()
+	fset := token.NewFileSet()
+	gen, err := parser.ParseFile(fset, "gen.go", src, 0)
+	require.NoError(t, err)
+	decls, err := parser.ParseFile(fset, "decls.go", "package "+c.Package+`
+type Item struct{}
+type v int
+type s int
+const n = 4
+`, 0)
+	require.NoError(t, err)
+	conf := types.Config{Importer: srcImporter}
+	_, err = conf.Check(c.Package, fset, []*ast.File{gen, decls}, nil)
+	assert.NoError(t, err, "type %s, key %s, value %s", c.Type, c.Key, c.Value)
+}
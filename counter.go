@@ -0,0 +1,145 @@
+// Copyright 2023 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swiss
+
+import (
+	"container/heap"
+	"sort"
+)
+
+// Counter is a hash map of keys to counts. Counts are
+// stored inline in the table's groups, and updated in
+// place with a single probe.
+type Counter[K comparable] struct {
+	m     *Map[K, int64]
+	total int64
+}
+
+// NewCounter constructs a Counter.
+func NewCounter[K comparable](sz uint32) *Counter[K] {
+	return &Counter[K]{m: NewMap[K, int64](sz)}
+}
+
+// Add adds |delta| to the count of |key|, and returns the new count.
+// Keys whose count drops to zero remain in the Counter.
+func (c *Counter[K]) Add(key K, delta int64) int64 {
+	n, _ := c.m.upsert(key)
+	*n += delta
+	c.total += delta
+	return *n
+}
+
+// Get returns the count of |key|, zero if absent.
+func (c *Counter[K]) Get(key K) (n int64) {
+	n, _ = c.m.Get(key)
+	return
+}
+
+// Delete attempts to remove |key|, returns true successful.
+func (c *Counter[K]) Delete(key K) (ok bool) {
+	var n int64
+	if n, ok = c.m.Get(key); ok {
+		c.m.Delete(key)
+		c.total -= n
+	}
+	return
+}
+
+// MergeFrom adds every count in |other| to |c|.
+func (c *Counter[K]) MergeFrom(other *Counter[K]) {
+	c.m.Merge(other.m, func(_ K, a, b int64) int64 {
+		return a + b
+	})
+	c.total += other.total
+}
+
+// Total returns the sum of all counts.
+func (c *Counter[K]) Total() int64 {
+	return c.total
+}
+
+// Count returns the number of distinct keys in the Counter.
+func (c *Counter[K]) Count() int {
+	return c.m.Count()
+}
+
+// Iter iterates the keys of the Counter and their counts,
+// passing them to the callback. It makes the same guarantees
+// as Map.Iter.
+func (c *Counter[K]) Iter(cb func(k K, n int64) (stop bool)) {
+	c.m.Iter(cb)
+}
+
+// Clear removes all keys from the Counter.
+func (c *Counter[K]) Clear() {
+	c.m.Clear()
+	c.total = 0
+}
+
+// CountEntry is a key and its count.
+type CountEntry[K comparable] struct {
+	Key   K
+	Count int64
+}
+
+// TopK returns the |k| keys with the highest counts, in descending
+// order of count. Ties are broken arbitrarily.
+func (c *Counter[K]) TopK(k int) []CountEntry[K] {
+	if k > c.Count() {
+		k = c.Count()
+	}
+	if k <= 0 {
+		return nil
+	}
+	// keep a min-heap of the |k| largest counts seen
+	h := make(countHeap[K], 0, k)
+	m := c.m
+	for g := uint64(0); g < uint64(m.ngroups); g++ {
+		meta, grp := m.at(g)
+		for s, ctrl := range meta {
+			if ctrl == empty || ctrl == tombstone {
+				continue
+			}
+			n := grp.values[s]
+			if len(h) < k {
+				heap.Push(&h, CountEntry[K]{Key: grp.keys[s], Count: n})
+			} else if n > h[0].Count {
+				h[0] = CountEntry[K]{Key: grp.keys[s], Count: n}
+				heap.Fix(&h, 0)
+			}
+		}
+	}
+	sort.Slice(h, func(i, j int) bool {
+		return h[i].Count > h[j].Count
+	})
+	return h
+}
+
+type countHeap[K comparable] []CountEntry[K]
+
+func (h countHeap[K]) Len() int           { return len(h) }
+func (h countHeap[K]) Less(i, j int) bool { return h[i].Count < h[j].Count }
+func (h countHeap[K]) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+func (h *countHeap[K]) Push(x any) {
+	*h = append(*h, x.(CountEntry[K]))
+}
+
+func (h *countHeap[K]) Pop() any {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
@@ -0,0 +1,81 @@
+// Copyright 2023 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swiss
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCounter(t *testing.T) {
+	keys := genStringData(16, 1000)
+	// keys[i] is counted |i| times
+	build := func(keys []string) *Counter[string] {
+		c := NewCounter[string](0)
+		for i, k := range keys {
+			for j := 0; j < i; j++ {
+				c.Add(k, 1)
+			}
+		}
+		return c
+	}
+	t.Run("add", func(t *testing.T) {
+		c := build(keys)
+		var total int64
+		for i, k := range keys {
+			assert.Equal(t, int64(i), c.Get(k))
+			total += int64(i)
+		}
+		assert.Equal(t, total, c.Total())
+		assert.Equal(t, len(keys)-1, c.Count())
+		assert.Equal(t, int64(-1), c.Add(keys[0], -1))
+		assert.Equal(t, len(keys), c.Count())
+		assert.Equal(t, total-1, c.Total())
+		assert.True(t, c.Delete(keys[1]))
+		assert.Equal(t, total-2, c.Total())
+		c.Clear()
+		assert.Equal(t, int64(0), c.Total())
+		assert.Equal(t, 0, c.Count())
+	})
+	t.Run("merge", func(t *testing.T) {
+		a, b := build(keys), build(keys[:500])
+		a.MergeFrom(b)
+		var total int64
+		for i, k := range keys {
+			exp := int64(i)
+			if i < 500 {
+				exp *= 2
+			}
+			assert.Equal(t, exp, a.Get(k))
+			total += exp
+		}
+		assert.Equal(t, total, a.Total())
+	})
+	t.Run("topk", func(t *testing.T) {
+		c := build(keys)
+		top := c.TopK(10)
+		assert.Equal(t, 10, len(top))
+		for i, e := range top {
+			j := len(keys) - 1 - i
+			assert.Equal(t, keys[j], e.Key)
+			assert.Equal(t, int64(j), e.Count)
+		}
+		assert.Equal(t, c.Count(), len(c.TopK(len(keys)*2)))
+		assert.Equal(t, c.Count(), len(c.TopK(math.MaxInt)))
+		assert.Nil(t, c.TopK(0))
+	})
+}
@@ -0,0 +1,185 @@
+// Copyright 2023 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swiss
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock is a source of the current time.
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// ExpiringMap is a hash map whose elements may expire. Expired
+// elements are dropped lazily when they are looked up, or eagerly
+// by Sweep. ExpiringMap is safe for concurrent use.
+type ExpiringMap[K comparable, V any] struct {
+	mu    sync.Mutex
+	m     *Map[K, expiring[V]]
+	clock Clock
+	stop  chan struct{}
+	done  chan struct{}
+}
+
+type expiring[V any] struct {
+	value V
+	// deadline in unix nanoseconds, zero if none
+	deadline int64
+}
+
+func (e expiring[V]) expired(now int64) bool {
+	return e.deadline != 0 && e.deadline <= now
+}
+
+// NewExpiringMap constructs an ExpiringMap. If |clock|
+// is nil, expiry is measured against the system clock.
+func NewExpiringMap[K comparable, V any](sz uint32, clock Clock) *ExpiringMap[K, V] {
+	if clock == nil {
+		clock = systemClock{}
+	}
+	return &ExpiringMap[K, V]{
+		m:     NewMap[K, expiring[V]](sz),
+		clock: clock,
+	}
+}
+
+// Put inserts |key| and |value| with no expiry.
+func (e *ExpiringMap[K, V]) Put(key K, value V) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.m.Put(key, expiring[V]{value: value})
+}
+
+// PutWithTTL inserts |key| and |value|, expiring after |ttl|.
+func (e *ExpiringMap[K, V]) PutWithTTL(key K, value V, ttl time.Duration) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	deadline := e.clock.Now().Add(ttl).UnixNano()
+	e.m.Put(key, expiring[V]{value: value, deadline: deadline})
+}
+
+// Get returns the |value| mapped by |key| if one exists and has not expired.
+func (e *ExpiringMap[K, V]) Get(key K) (value V, ok bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	var x expiring[V]
+	if x, ok = e.m.Get(key); !ok {
+		return
+	}
+	if x.expired(e.clock.Now().UnixNano()) {
+		e.m.Delete(key)
+		ok = false
+		return
+	}
+	value = x.value
+	return
+}
+
+// Has returns true if |key| is present in |e| and has not expired.
+func (e *ExpiringMap[K, V]) Has(key K) (ok bool) {
+	_, ok = e.Get(key)
+	return
+}
+
+// Delete attempts to remove |key|, returns true successful.
+func (e *ExpiringMap[K, V]) Delete(key K) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.m.Delete(key)
+}
+
+// Sweep removes every element that has expired as of
+// |now|, and returns the number of elements removed.
+func (e *ExpiringMap[K, V]) Sweep(now time.Time) int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	ts := now.UnixNano()
+	return e.m.DeleteFunc(func(_ K, x expiring[V]) bool {
+		return x.expired(ts)
+	})
+}
+
+// Iter iterates the unexpired elements of the ExpiringMap, passing
+// them to the callback. The callback must not call methods on |e|.
+func (e *ExpiringMap[K, V]) Iter(cb func(k K, v V) (stop bool)) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	now := e.clock.Now().UnixNano()
+	e.m.Iter(func(k K, x expiring[V]) (stop bool) {
+		if x.expired(now) {
+			return
+		}
+		return cb(k, x.value)
+	})
+}
+
+// Count returns the number of elements in the ExpiringMap,
+// including expired elements that have not yet been removed.
+func (e *ExpiringMap[K, V]) Count() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.m.Count()
+}
+
+// StartJanitor starts a background goroutine that calls Sweep every
+// |interval|, until Close is called. |interval| must be positive.
+func (e *ExpiringMap[K, V]) StartJanitor(interval time.Duration) {
+	if interval <= 0 {
+		// time.NewTicker would panic in the janitor goroutine
+		panic("swiss: non-positive janitor interval")
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.stop != nil {
+		panic("swiss: janitor already started")
+	}
+	e.stop, e.done = make(chan struct{}), make(chan struct{})
+	go e.janitor(interval, e.stop, e.done)
+}
+
+func (e *ExpiringMap[K, V]) janitor(interval time.Duration, stop, done chan struct{}) {
+	defer close(done)
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			e.Sweep(e.clock.Now())
+		case <-stop:
+			return
+		}
+	}
+}
+
+// Close stops the janitor goroutine, if one was started,
+// and waits for it to exit.
+func (e *ExpiringMap[K, V]) Close() {
+	e.mu.Lock()
+	stop, done := e.stop, e.done
+	e.stop, e.done = nil, nil
+	e.mu.Unlock()
+	if stop != nil {
+		close(stop)
+		<-done
+	}
+}
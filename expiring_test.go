@@ -0,0 +1,129 @@
+// Copyright 2023 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swiss
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func TestExpiringMap(t *testing.T) {
+	t.Run("uint32=1000", func(t *testing.T) {
+		testExpiringMap(t, genUint32Data(1000))
+	})
+	t.Run("strings=10_000", func(t *testing.T) {
+		testExpiringMap(t, genStringData(16, 10_000))
+	})
+}
+
+func testExpiringMap[K comparable](t *testing.T, keys []K) {
+	// keys[i] expires after |i| seconds,
+	// keys[0] never expires
+	setup := func() (*ExpiringMap[K, int], *fakeClock) {
+		clock := &fakeClock{now: time.Unix(1_000_000, 0)}
+		e := NewExpiringMap[K, int](0, clock)
+		e.Put(keys[0], 0)
+		for i, k := range keys[1:] {
+			e.PutWithTTL(k, i+1, time.Duration(i+1)*time.Second)
+		}
+		return e, clock
+	}
+	t.Run("lazy", func(t *testing.T) {
+		e, clock := setup()
+		clock.advance(time.Duration(len(keys)/2) * time.Second)
+		for i, k := range keys {
+			v, ok := e.Get(k)
+			live := i == 0 || i > len(keys)/2
+			assert.Equal(t, live, ok)
+			assert.Equal(t, live, e.Has(k))
+			if live {
+				assert.Equal(t, i, v)
+			}
+		}
+		assert.Equal(t, len(keys)-len(keys)/2, e.Count())
+	})
+	t.Run("sweep", func(t *testing.T) {
+		e, clock := setup()
+		n := e.Sweep(clock.Now())
+		assert.Equal(t, 0, n)
+		n = e.Sweep(clock.Now().Add(time.Duration(len(keys)/2) * time.Second))
+		assert.Equal(t, len(keys)/2, n)
+		assert.Equal(t, len(keys)-len(keys)/2, e.Count())
+		// clock has not moved, Get does not expire anything
+		for i, k := range keys {
+			_, ok := e.Get(k)
+			assert.Equal(t, i == 0 || i > len(keys)/2, ok)
+		}
+		clock.advance(time.Duration(len(keys)) * time.Second)
+		var n2 int
+		e.Iter(func(k K, v int) (stop bool) {
+			assert.Equal(t, keys[0], k)
+			n2++
+			return
+		})
+		assert.Equal(t, 1, n2)
+		e.Sweep(clock.Now())
+		assert.Equal(t, 1, e.Count())
+	})
+	t.Run("overwrite", func(t *testing.T) {
+		e, clock := setup()
+		e.Put(keys[1], -1)
+		e.PutWithTTL(keys[0], 0, time.Second)
+		clock.advance(time.Hour)
+		_, ok := e.Get(keys[0])
+		assert.False(t, ok)
+		v, ok := e.Get(keys[1])
+		assert.True(t, ok)
+		assert.Equal(t, -1, v)
+		assert.True(t, e.Delete(keys[1]))
+		assert.False(t, e.Has(keys[1]))
+	})
+	t.Run("janitor", func(t *testing.T) {
+		e, clock := setup()
+		clock.advance(time.Duration(len(keys)) * time.Second)
+		e.StartJanitor(time.Millisecond)
+		assert.Eventually(t, func() bool {
+			return e.Count() == 1
+		}, 5*time.Second, time.Millisecond)
+		e.Close()
+		e.Close()
+		// invalid intervals panic in the caller
+		e, _ = setup()
+		assert.Panics(t, func() { e.StartJanitor(0) })
+		assert.Panics(t, func() { e.StartJanitor(-time.Second) })
+		e.StartJanitor(time.Millisecond)
+		e.Close()
+	})
+}
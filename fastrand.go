@@ -24,6 +24,7 @@ import (
 func fastrand() uint32
 
 // randIntN returns a random number in the interval [0, n).
-func randIntN(n int) uint32 {
-	return fastModN(fastrand(), uint32(n))
+func randIntN(n int) uint64 {
+	x := uint64(fastrand())<<32 | uint64(fastrand())
+	return fastModN(x, uint64(n))
 }
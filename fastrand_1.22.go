@@ -21,6 +21,6 @@ import (
 )
 
 // randIntN returns a random number in the interval [0, n).
-func randIntN(n int) uint32 {
-	return rand.Uint32N(uint32(n))
+func randIntN(n int) uint64 {
+	return rand.Uint64N(uint64(n))
 }
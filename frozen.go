@@ -0,0 +1,512 @@
+// Copyright 2023 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swiss
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/bits"
+	"reflect"
+	"unsafe"
+)
+
+// The frozen format is a fixed size header followed by the control
+// bytes, reaches and groups of a table, each aligned to |frozenAlign| so that
+// a file mapped into memory can be probed in place. Numbers and the
+// table itself are stored in the byte order of the writer, which is
+// recorded in the header and checked by the reader.
+const (
+	frozenMagic     = "SWISSFRZ"
+	frozenVersion   = 2
+	frozenByteOrder = 0x01020304
+	frozenAlign     = 64
+	// frozenLoad is the maximum load of a frozen table in sixteenths of
+	// its slots, denser than the |maxAvgGroupLoad| of mutable tables.
+	// Runs of full groups grow long at this load, so lookups are bounded
+	// by the reach of their home group rather than by an empty slot.
+	frozenLoad = 15
+	// frozenUnbounded is the reach of a group whose elements are too
+	// far from home to record, lookups homed there end at an empty slot
+	frozenUnbounded = math.MaxUint8
+)
+
+var (
+	// ErrFrozenFormat is returned when opening data that is not a FrozenMap.
+	ErrFrozenFormat = errors.New("swiss: not a frozen map")
+	// ErrFrozenVersion is returned when opening a FrozenMap written
+	// in a format version this package does not support.
+	ErrFrozenVersion = errors.New("swiss: unsupported frozen map version")
+	// ErrFrozenByteOrder is returned when opening a FrozenMap written
+	// on a machine of different endianness.
+	ErrFrozenByteOrder = errors.New("swiss: frozen map byte order mismatch")
+	// ErrFrozenLayout is returned when opening a FrozenMap whose group
+	// size or key and value sizes do not match this build.
+	ErrFrozenLayout = errors.New("swiss: frozen map layout mismatch")
+)
+
+// frozenHeader is the first |frozenAlign| bytes of the frozen format.
+type frozenHeader struct {
+	magic     [8]byte
+	version   uint32
+	byteOrder uint32
+	groupSize uint32
+	keySize   uint32
+	valueSize uint32
+	groupLen  uint32
+	seed      uint64
+	groups    uint64
+	count     uint64
+	_         [frozenAlign - 56]byte
+}
+
+// FrozenMap is an immutable hash map, built by Map.Freeze or read from
+// the frozen format, that is safe for concurrent readers. Its table is
+// sized to its elements, leaving no room for inserts or tombstones.
+//
+// A FrozenMap read from the frozen format may be backed by a file
+// mapped into memory. Its keys are hashed with a portable function of
+// their bytes, rather than the per-process runtime hash, so a table
+// written by one process can be probed by another.
+type FrozenMap[K comparable, V any] struct {
+	ctrl   []metadata
+	groups []group[K, V]
+	// reach is the number of groups probed by lookups homed in each
+	// group, so that lookups of absent keys end early, see buildFrozen
+	reach []uint8
+	count int
+	// hasher hashes keys unless |portable| is set,
+	// in which case keys are hashed by bytesHash
+	hasher   keyHasher[K]
+	portable bool
+	seed     uint64
+	// unmap releases the file mapping, if any
+	unmap func() error
+}
+
+// Freeze returns an immutable copy of |m| with a compact table. The
+// FrozenMap is unaffected by later changes to |m|.
+func (m *Map[K, V]) Freeze() *FrozenMap[K, V] {
+	f := &FrozenMap[K, V]{
+		count:  m.Count(),
+		hasher: m.hash,
+	}
+	f.ctrl, f.groups, f.reach = buildFrozen(m, f.hasher.hash)
+	return f
+}
+
+// WriteFrozen writes the elements of |m| to |w| in the format read by
+// OpenFrozenMap and LoadFrozenMap. K and V must be free of pointers,
+// K must also be free of padding and floating point fields, so that
+// keys are equal exactly when their bytes are.
+func (m *Map[K, V]) WriteFrozen(w io.Writer) error {
+	if err := checkFrozenTypes[K, V](); err != nil {
+		return err
+	}
+	seed := randIntN(math.MaxInt32)<<32 | randIntN(math.MaxInt32)
+	ctrl, groups, reach := buildFrozen(m, func(k K) uint64 {
+		return bytesHash(seed, asBytes(&k, 1))
+	})
+
+	hdr := frozenHeader{
+		version:   frozenVersion,
+		byteOrder: frozenByteOrder,
+		groupSize: groupSize,
+		keySize:   uint32(unsafe.Sizeof(*new(K))),
+		valueSize: uint32(unsafe.Sizeof(*new(V))),
+		groupLen:  uint32(unsafe.Sizeof(group[K, V]{})),
+		seed:      seed,
+		groups:    uint64(len(groups)),
+		count:     uint64(m.Count()),
+	}
+	copy(hdr.magic[:], frozenMagic)
+	if _, err := w.Write(asBytes(&hdr, 1)); err != nil {
+		return err
+	}
+	if _, err := w.Write(asBytes(&ctrl[0], len(ctrl))); err != nil {
+		return err
+	}
+	var pad [frozenAlign]byte
+	if n := frozenAligned(len(ctrl)*groupSize) - len(ctrl)*groupSize; n > 0 {
+		if _, err := w.Write(pad[:n]); err != nil {
+			return err
+		}
+	}
+	if _, err := w.Write(reach); err != nil {
+		return err
+	}
+	if n := frozenAligned(len(reach)) - len(reach); n > 0 {
+		if _, err := w.Write(pad[:n]); err != nil {
+			return err
+		}
+	}
+	_, err := w.Write(asBytes(&groups[0], len(groups)))
+	return err
+}
+
+// LoadFrozenMap returns a FrozenMap backed by |data|, which must
+// not be modified while the FrozenMap is in use. The control bytes
+// are checked, so that lookups in a corrupt table terminate.
+func LoadFrozenMap[K comparable, V any](data []byte) (*FrozenMap[K, V], error) {
+	if err := checkFrozenTypes[K, V](); err != nil {
+		return nil, err
+	}
+	if len(data) < frozenAlign {
+		return nil, ErrFrozenFormat
+	}
+	if uintptr(unsafe.Pointer(&data[0]))%8 != 0 {
+		// groups are read in place, copy to align them
+		buf := make([]uint64, (len(data)+7)/8)
+		b := asBytes(&buf[0], len(buf))
+		data = b[:copy(b, data)]
+	}
+	base := unsafe.Pointer(&data[0])
+	hdr := (*frozenHeader)(base)
+	if string(hdr.magic[:]) != frozenMagic {
+		return nil, ErrFrozenFormat
+	}
+	if hdr.byteOrder != frozenByteOrder {
+		return nil, ErrFrozenByteOrder
+	}
+	if hdr.version != frozenVersion {
+		return nil, fmt.Errorf("%w: %d", ErrFrozenVersion, hdr.version)
+	}
+	if hdr.groupSize != groupSize ||
+		hdr.keySize != uint32(unsafe.Sizeof(*new(K))) ||
+		hdr.valueSize != uint32(unsafe.Sizeof(*new(V))) ||
+		hdr.groupLen != uint32(unsafe.Sizeof(group[K, V]{})) {
+		return nil, ErrFrozenLayout
+	}
+	n := hdr.groups
+	if n == 0 || n > uint64(len(data)) || hdr.count > n*groupSize {
+		return nil, ErrFrozenFormat
+	}
+	reachOff := uint64(frozenAlign + frozenAligned(int(n)*groupSize))
+	off := reachOff + uint64(frozenAligned(int(n)))
+	if uint64(len(data)) < off+n*uint64(hdr.groupLen) {
+		return nil, fmt.Errorf("%w: truncated", ErrFrozenFormat)
+	}
+	ctrl := unsafe.Slice((*metadata)(unsafe.Add(base, frozenAlign)), n)
+	if err := checkFrozenCtrl(ctrl, hdr.count); err != nil {
+		return nil, err
+	}
+	return &FrozenMap[K, V]{
+		ctrl:     ctrl,
+		groups:   unsafe.Slice((*group[K, V])(unsafe.Add(base, off)), n),
+		reach:    data[reachOff : reachOff+n],
+		count:    int(hdr.count),
+		portable: true,
+		seed:     hdr.seed,
+	}, nil
+}
+
+// checkFrozenCtrl checks that |ctrl| holds |count| full slots and
+// otherwise only empty slots, at least one of which ends probing.
+func checkFrozenCtrl(ctrl []metadata, count uint64) error {
+	var full uint64
+	for g := range ctrl {
+		for _, c := range ctrl[g] {
+			switch {
+			case c >= 0:
+				full++
+			case c != empty:
+				return fmt.Errorf("%w: invalid control byte %#x", ErrFrozenFormat, uint8(c))
+			}
+		}
+	}
+	if full != count {
+		return fmt.Errorf("%w: %d elements, expected %d", ErrFrozenFormat, full, count)
+	}
+	if full == uint64(len(ctrl))*groupSize {
+		return fmt.Errorf("%w: no empty slots", ErrFrozenFormat)
+	}
+	return nil
+}
+
+// OpenFrozenMap maps the file at |path|, written by Map.WriteFrozen,
+// into memory. The FrozenMap must be closed to release the mapping.
+func OpenFrozenMap[K comparable, V any](path string) (*FrozenMap[K, V], error) {
+	data, unmap, err := mapFile(path)
+	if err != nil {
+		return nil, err
+	}
+	f, err := LoadFrozenMap[K, V](data)
+	if err != nil {
+		_ = unmap()
+		return nil, err
+	}
+	f.unmap = unmap
+	return f, nil
+}
+
+// Close releases the memory mapping of a FrozenMap returned
+// by OpenFrozenMap. The FrozenMap must not be used after.
+func (f *FrozenMap[K, V]) Close() (err error) {
+	if f.unmap != nil {
+		err = f.unmap()
+		f.unmap = nil
+	}
+	f.ctrl, f.groups, f.reach, f.count = nil, nil, nil, 0
+	return
+}
+
+// Has returns true if |key| is present in |f|.
+func (f *FrozenMap[K, V]) Has(key K) (ok bool) {
+	_, ok = f.Get(key)
+	return
+}
+
+// Get returns the |value| mapped by |key| if one exists.
+func (f *FrozenMap[K, V]) Get(key K) (value V, ok bool) {
+	var g uint64
+	var s uint32
+	if g, s, _, ok = f.find(key); ok {
+		value = f.groups[g].values[s]
+	}
+	return
+}
+
+// find returns the location of |key| if present,
+// and the number of groups |probed| to find it.
+func (f *FrozenMap[K, V]) find(key K) (g uint64, s uint32, probed int, ok bool) {
+	if len(f.groups) == 0 {
+		return
+	}
+	hi, lo := splitHash(f.hash(key))
+	g = probeStart(hi, len(f.groups))
+	reach := int(f.reach[g])
+	for probed < reach || reach == frozenUnbounded {
+		probed++
+		matches := metaMatchH2(&f.ctrl[g], lo)
+		for matches != 0 {
+			s = nextMatch(&matches)
+			if key == f.groups[g].keys[s] {
+				return g, s, probed, true
+			}
+		}
+		// |key| is not in group |g|,
+		// stop probing if we see an empty slot
+		if metaMatchEmpty(&f.ctrl[g]) != 0 {
+			return
+		}
+		g += 1 // linear probing
+		if g >= uint64(len(f.groups)) {
+			g = 0
+		}
+	}
+	return
+}
+
+// Iter iterates the elements of the FrozenMap, passing them to the
+// callback. Elements are visited in the same order on every call.
+func (f *FrozenMap[K, V]) Iter(cb func(k K, v V) (stop bool)) {
+	for g := range f.ctrl {
+		for s, c := range f.ctrl[g] {
+			if c == empty {
+				continue
+			}
+			if stop := cb(f.groups[g].keys[s], f.groups[g].values[s]); stop {
+				return
+			}
+		}
+	}
+}
+
+// Count returns the number of elements in the FrozenMap.
+func (f *FrozenMap[K, V]) Count() int {
+	return f.count
+}
+
+func (f *FrozenMap[K, V]) hash(key K) uint64 {
+	if f.portable {
+		return bytesHash(f.seed, asBytes(&key, 1))
+	}
+	return f.hasher.hash(key)
+}
+
+// buildFrozen builds a table of the elements of |m| hashed by |hash|,
+// loaded to |frozenLoad|. The table has no tombstones.
+//
+// Elements are placed in the order of their home groups, so that those
+// homed in a group are stored together, and the |reach| of each group
+// records how many groups hold its elements. Linear probing fills the
+// same slots in any order, but this order keeps every element as close
+// to home as possible, and lets lookups end at the group's reach.
+func buildFrozen[K comparable, V any](m *Map[K, V], hash func(K) uint64) (ctrl []metadata, groups []group[K, V], reach []uint8) {
+	n := frozenGroups(uint64(m.Count()))
+	ctrl = make([]metadata, n)
+	groups = make([]group[K, V], n)
+	reach = make([]uint8, n)
+	for i := range ctrl {
+		ctrl[i] = newEmptyMetadata()
+	}
+	type elem struct {
+		key   K
+		value V
+		home  uint64
+		lo    h2
+	}
+	elems := make([]elem, 0, m.Count())
+	m.Iter(func(k K, v V) (stop bool) {
+		hi, lo := splitHash(hash(k))
+		elems = append(elems, elem{key: k, value: v, home: probeStart(hi, int(n)), lo: lo})
+		return
+	})
+	// counting sort by home group
+	start := make([]int, n+1)
+	for _, e := range elems {
+		start[e.home+1]++
+	}
+	for g := uint64(1); g <= n; g++ {
+		start[g] += start[g-1]
+	}
+	sorted := make([]elem, len(elems))
+	next := append([]int(nil), start[:n]...)
+	for _, e := range elems {
+		sorted[next[e.home]] = e
+		next[e.home]++
+	}
+	// the last |wrap| elements overflow the last group into the first,
+	// ahead of the elements homed there, find how many once settled
+	var wrap int
+	for {
+		carry := wrap
+		for g := uint64(0); g < n; g++ {
+			carry += start[g+1] - start[g]
+			if carry -= groupSize; carry < 0 {
+				carry = 0
+			}
+		}
+		if carry == wrap {
+			break
+		}
+		wrap = carry
+	}
+	// the next free slot is slot |s| of group |g|
+	var g, s uint64
+	place := func(e elem) {
+		r := (g+n-e.home)%n + 1
+		if r >= frozenUnbounded {
+			reach[e.home] = frozenUnbounded
+		} else if uint8(r) > reach[e.home] {
+			reach[e.home] = uint8(r)
+		}
+		groups[g].keys[s] = e.key
+		groups[g].values[s] = e.value
+		ctrl[g][s] = int8(e.lo)
+		if s++; s == groupSize {
+			g, s = g+1, 0
+		}
+	}
+	tail := len(sorted) - wrap
+	for _, e := range sorted[tail:] {
+		place(e)
+	}
+	for _, e := range sorted[:tail] {
+		if g < e.home {
+			g, s = e.home, 0
+		}
+		place(e)
+	}
+	return
+}
+
+// frozenGroups returns the number of groups in a frozen
+// table of |count| elements, loaded to |frozenLoad|.
+func frozenGroups(count uint64) (groups uint64) {
+	// ceil(count * 16 / (frozenLoad * groupSize))
+	hi, lo := bits.Mul64(count, 16/groupSize)
+	groups, r := bits.Div64(hi, lo, frozenLoad)
+	if r != 0 || groups == 0 {
+		groups++
+	}
+	return
+}
+
+// frozenAligned returns |n| bytes padded to |frozenAlign|.
+func frozenAligned(n int) int {
+	return (n + frozenAlign - 1) &^ (frozenAlign - 1)
+}
+
+// checkFrozenTypes returns an error if K or V cannot be stored in the
+// frozen format. Values need only be free of pointers, keys must also
+// compare equal exactly when their bytes are equal.
+func checkFrozenTypes[K comparable, V any]() error {
+	if t := reflect.TypeOf((*K)(nil)).Elem(); !flatType(t, true) {
+		return fmt.Errorf("swiss: key type %s cannot be frozen", t)
+	}
+	if t := reflect.TypeOf((*V)(nil)).Elem(); !flatType(t, false) {
+		return fmt.Errorf("swiss: value type %s cannot be frozen", t)
+	}
+	return nil
+}
+
+// flatType returns true if |t| holds no pointers. If |exact| is set,
+// |t| must also have no padding and no floating point components.
+func flatType(t reflect.Type, exact bool) bool {
+	switch t.Kind() {
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return true
+	case reflect.Float32, reflect.Float64, reflect.Complex64, reflect.Complex128:
+		return !exact
+	case reflect.Array:
+		return flatType(t.Elem(), exact)
+	case reflect.Struct:
+		var sz uintptr
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !flatType(f.Type, exact) {
+				return false
+			}
+			sz += f.Type.Size()
+		}
+		return !exact || sz == t.Size()
+	default:
+		return false
+	}
+}
+
+// asBytes returns the memory of |n| values starting at |p|.
+func asBytes[T any](p *T, n int) []byte {
+	return unsafe.Slice((*byte)(unsafe.Pointer(p)), uintptr(n)*unsafe.Sizeof(*p))
+}
+
+// bytesHash is a seeded hash of |b| that is stable across processes,
+// built from the multiply-mix of wyhash.
+func bytesHash(seed uint64, b []byte) uint64 {
+	const (
+		p0 = 0xa0761d6478bd642f
+		p1 = 0xe7037ed1a0b428db
+		p2 = 0x8ebc6af09c88c6e3
+	)
+	h, n := seed^p0, len(b)
+	for ; len(b) >= 8; b = b[8:] {
+		h = mulMix(binary.LittleEndian.Uint64(b)^p1, h^p2)
+	}
+	if len(b) > 0 {
+		var tail [8]byte
+		copy(tail[:], b)
+		h = mulMix(binary.LittleEndian.Uint64(tail[:])^p1, h^p2)
+	}
+	return mulMix(h^p2, uint64(n)^p1)
+}
+
+func mulMix(a, b uint64) uint64 {
+	hi, lo := bits.Mul64(a, b)
+	return hi ^ lo
+}
@@ -0,0 +1,33 @@
+// Copyright 2023 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly)
+
+package swiss
+
+import (
+	"os"
+)
+
+// mapFile reads the file at |path| into memory,
+// on platforms without mmap support.
+func mapFile(path string) (data []byte, unmap func() error, err error) {
+	if data, err = os.ReadFile(path); err != nil {
+		return nil, nil, err
+	}
+	unmap = func() error {
+		return nil
+	}
+	return
+}
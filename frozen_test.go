@@ -0,0 +1,381 @@
+// Copyright 2023 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swiss
+
+import (
+	"bytes"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type frozenKey struct {
+	A uint32
+	B [3]uint16
+	C uint16
+}
+
+type frozenValue struct {
+	X float64
+	Y int8
+}
+
+func TestFrozenMap(t *testing.T) {
+	t.Run("uint32=0", func(t *testing.T) {
+		testFrozenMap(t, genUint32Data(0))
+	})
+	t.Run("uint32=1000", func(t *testing.T) {
+		testFrozenMap(t, genUint32Data(1000))
+	})
+	t.Run("uint32=100_000", func(t *testing.T) {
+		testFrozenMap(t, genUint32Data(100_000))
+	})
+	t.Run("struct=1000", func(t *testing.T) {
+		keys := make([]frozenKey, 1000)
+		for i := range keys {
+			keys[i] = frozenKey{A: uint32(i), B: [3]uint16{uint16(i), 1, 2}, C: 7}
+		}
+		testFrozenMap(t, keys)
+	})
+}
+
+func testFrozenMap[K comparable](t *testing.T, keys []K) {
+	m := NewMap[K, frozenValue](0)
+	for i, k := range keys {
+		m.Put(k, frozenValue{X: float64(i), Y: int8(i)})
+	}
+	check := func(t *testing.T, f *FrozenMap[K, frozenValue]) {
+		assert.Equal(t, len(keys), f.Count())
+		for i, k := range keys {
+			v, ok := f.Get(k)
+			assert.True(t, ok)
+			assert.Equal(t, frozenValue{X: float64(i), Y: int8(i)}, v)
+			assert.True(t, f.Has(k))
+		}
+		var n int
+		f.Iter(func(k K, v frozenValue) (stop bool) {
+			exp, ok := m.Get(k)
+			assert.True(t, ok)
+			assert.Equal(t, exp, v)
+			n++
+			return
+		})
+		assert.Equal(t, len(keys), n)
+	}
+	var buf bytes.Buffer
+	require.NoError(t, m.WriteFrozen(&buf))
+
+	t.Run("load", func(t *testing.T) {
+		f, err := LoadFrozenMap[K, frozenValue](buf.Bytes())
+		require.NoError(t, err)
+		check(t, f)
+		assert.NoError(t, f.Close())
+		assert.Equal(t, 0, f.Count())
+	})
+	t.Run("misaligned", func(t *testing.T) {
+		data := make([]byte, buf.Len()+1)
+		copy(data[1:], buf.Bytes())
+		f, err := LoadFrozenMap[K, frozenValue](data[1:])
+		require.NoError(t, err)
+		check(t, f)
+	})
+	t.Run("open", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "frozen")
+		require.NoError(t, os.WriteFile(path, buf.Bytes(), 0644))
+		f, err := OpenFrozenMap[K, frozenValue](path)
+		require.NoError(t, err)
+		check(t, f)
+		assert.NoError(t, f.Close())
+	})
+}
+
+func TestFrozenMapAbsent(t *testing.T) {
+	keys := genUint32Data(1000)
+	m := NewMap[uint32, uint32](0)
+	for _, k := range keys[:500] {
+		m.Put(k, k)
+	}
+	var buf bytes.Buffer
+	require.NoError(t, m.WriteFrozen(&buf))
+	f, err := LoadFrozenMap[uint32, uint32](buf.Bytes())
+	require.NoError(t, err)
+	for _, k := range keys[500:] {
+		assert.False(t, f.Has(k))
+	}
+	f.Iter(func(k, v uint32) (stop bool) {
+		assert.Equal(t, k, v)
+		return true
+	})
+}
+
+func TestFrozenMapErrors(t *testing.T) {
+	m := NewMap[uint64, uint32](0)
+	for i := uint64(0); i < 100; i++ {
+		m.Put(i, uint32(i))
+	}
+	var buf bytes.Buffer
+	require.NoError(t, m.WriteFrozen(&buf))
+	good := buf.Bytes()
+	corrupt := func(fn func(h *frozenHeader)) []byte {
+		data := append([]byte(nil), good...)
+		fn((*frozenHeader)(unsafe.Pointer(&data[0])))
+		return data
+	}
+
+	_, err := LoadFrozenMap[uint64, uint32](good[:10])
+	assert.ErrorIs(t, err, ErrFrozenFormat)
+	_, err = LoadFrozenMap[uint64, uint32](good[:len(good)-1])
+	assert.ErrorIs(t, err, ErrFrozenFormat)
+	_, err = LoadFrozenMap[uint64, uint32](corrupt(func(h *frozenHeader) {
+		h.magic[0] = 'X'
+	}))
+	assert.ErrorIs(t, err, ErrFrozenFormat)
+	_, err = LoadFrozenMap[uint64, uint32](corrupt(func(h *frozenHeader) {
+		h.version = frozenVersion + 1
+	}))
+	assert.ErrorIs(t, err, ErrFrozenVersion)
+	_, err = LoadFrozenMap[uint64, uint32](corrupt(func(h *frozenHeader) {
+		h.byteOrder = 0x04030201
+	}))
+	assert.ErrorIs(t, err, ErrFrozenByteOrder)
+	_, err = LoadFrozenMap[uint64, uint32](corrupt(func(h *frozenHeader) {
+		h.groupSize = 2 * groupSize
+	}))
+	assert.ErrorIs(t, err, ErrFrozenLayout)
+	// mismatched key and value types
+	_, err = LoadFrozenMap[uint32, uint32](good)
+	assert.ErrorIs(t, err, ErrFrozenLayout)
+	_, err = LoadFrozenMap[uint64, uint64](good)
+	assert.ErrorIs(t, err, ErrFrozenLayout)
+
+	// control bytes
+	ctrl := func(data []byte) []byte {
+		h := (*frozenHeader)(unsafe.Pointer(&data[0]))
+		return data[frozenAlign : frozenAlign+h.groups*groupSize]
+	}
+	bad := corrupt(func(h *frozenHeader) {})
+	ctrl(bad)[0] = 0xfd
+	_, err = LoadFrozenMap[uint64, uint32](bad)
+	assert.ErrorIs(t, err, ErrFrozenFormat)
+	bad = corrupt(func(h *frozenHeader) {
+		h.count--
+	})
+	_, err = LoadFrozenMap[uint64, uint32](bad)
+	assert.ErrorIs(t, err, ErrFrozenFormat)
+	// a table without empty slots would never end a probe
+	bad = corrupt(func(h *frozenHeader) {
+		h.count = h.groups * groupSize
+	})
+	for i, c := range ctrl(bad) {
+		if int8(c) == empty {
+			ctrl(bad)[i] = 0
+		}
+	}
+	_, err = LoadFrozenMap[uint64, uint32](bad)
+	assert.ErrorIs(t, err, ErrFrozenFormat)
+	assert.ErrorContains(t, err, "no empty slots")
+
+	_, err = OpenFrozenMap[uint64, uint32](filepath.Join(t.TempDir(), "missing"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestFrozenTypes(t *testing.T) {
+	assert.NoError(t, checkFrozenTypes[uint64, frozenValue]())
+	assert.NoError(t, checkFrozenTypes[frozenKey, [4]int]())
+	// pointers
+	assert.Error(t, checkFrozenTypes[string, int]())
+	assert.Error(t, checkFrozenTypes[int, []int]())
+	assert.Error(t, checkFrozenTypes[*int, int]())
+	assert.Error(t, checkFrozenTypes[int, struct{ p *int }]())
+	// keys whose equality is not bytewise
+	assert.Error(t, checkFrozenTypes[float64, int]())
+	assert.Error(t, checkFrozenTypes[frozenValue, int]())
+	type padded struct {
+		a uint8
+		b uint64
+	}
+	assert.Error(t, checkFrozenTypes[padded, int]())
+	assert.NoError(t, checkFrozenTypes[int, padded]())
+
+	m := NewMap[string, int](0)
+	assert.Error(t, m.WriteFrozen(&bytes.Buffer{}))
+	_, err := LoadFrozenMap[string, int](make([]byte, 128))
+	assert.Error(t, err)
+}
+
+func TestBytesHash(t *testing.T) {
+	// the frozen format depends on these
+	// values being stable across processes
+	assert.Equal(t, uint64(0x55ba897d2af3577c), bytesHash(0, nil))
+	assert.Equal(t, uint64(0xf086247c93a44203), bytesHash(0, []byte("swiss")))
+	assert.Equal(t, uint64(0x2b24e1ca0b8db160), bytesHash(42, []byte("0123456789abcdef!")))
+	assert.NotEqual(t, bytesHash(0, []byte("swiss")), bytesHash(1, []byte("swiss")))
+	assert.NotEqual(t, bytesHash(0, []byte{0}), bytesHash(0, []byte{0, 0}))
+}
+
+func TestFreeze(t *testing.T) {
+	t.Run("strings=0", func(t *testing.T) {
+		testFreeze(t, genStringData(16, 0))
+	})
+	t.Run("strings=100", func(t *testing.T) {
+		testFreeze(t, genStringData(16, 100))
+	})
+	t.Run("uint32=1000", func(t *testing.T) {
+		testFreeze(t, genUint32Data(1000))
+	})
+	t.Run("strings=10_000", func(t *testing.T) {
+		testFreeze(t, genStringData(16, 10_000))
+	})
+	t.Run("uint32=100_000", func(t *testing.T) {
+		testFreeze(t, genUint32Data(100_000))
+	})
+}
+
+func testFreeze[K comparable](t *testing.T, keys []K) {
+	m := NewMap[K, int](0)
+	for i, k := range keys {
+		m.Put(k, i)
+	}
+	// leave tombstones behind
+	for _, k := range keys[len(keys)/2:] {
+		m.Delete(k)
+	}
+	live := keys[:len(keys)/2]
+	f := m.Freeze()
+	assert.Equal(t, len(live), f.Count())
+	assert.LessOrEqual(t, len(f.groups), m.ngroups)
+	assert.Equal(t, int(frozenGroups(uint64(len(live)))), len(f.groups))
+	for _, c := range f.ctrl {
+		for _, b := range c {
+			assert.NotEqual(t, tombstone, b)
+		}
+	}
+
+	// |f| is unaffected by changes to |m|
+	m.Clear()
+	for i, k := range live {
+		v, ok := f.Get(k)
+		assert.True(t, ok)
+		assert.Equal(t, i, v)
+	}
+	for _, k := range keys[len(keys)/2:] {
+		assert.False(t, f.Has(k))
+	}
+	seen := make(map[K]int, len(live))
+	f.Iter(func(k K, v int) (stop bool) {
+		seen[k]++
+		assert.Equal(t, k, live[v])
+		return
+	})
+	assert.Equal(t, len(live), len(seen))
+
+	// concurrent readers
+	done := make(chan struct{})
+	for r := 0; r < 4; r++ {
+		go func() {
+			defer func() { done <- struct{}{} }()
+			for i, k := range live {
+				if v, ok := f.Get(k); !ok || v != i {
+					t.Errorf("missing key %v", k)
+					return
+				}
+			}
+		}()
+	}
+	for r := 0; r < 4; r++ {
+		<-done
+	}
+}
+
+func TestFrozenGroups(t *testing.T) {
+	assert.Equal(t, uint64(1), frozenGroups(0))
+	assert.Equal(t, uint64(1), frozenGroups(1))
+	for n := uint64(1); n < 10_000; n++ {
+		// tables keep at least one empty slot
+		assert.Greater(t, frozenGroups(n)*groupSize, n)
+		assert.LessOrEqual(t, frozenGroups(n), numGroups(n, maxAvgGroupLoad))
+	}
+	assert.Equal(t, uint64(16*1000/groupSize), frozenGroups(15*1000))
+	assert.Equal(t, uint64(16*1000/groupSize+1), frozenGroups(15*1000+1))
+	// frozen tables are denser than mutable ones
+	assert.Less(t, frozenGroups(1_000_000), numGroups(1_000_000, maxAvgGroupLoad))
+	assert.Less(t, frozenGroups(math.MaxUint64), numGroups(math.MaxUint64, maxAvgGroupLoad))
+}
+
+func TestFrozenProbes(t *testing.T) {
+	const n = 1_000_000
+	keys := genUint32Data(2 * n)
+	m := NewMap[uint32, int](n)
+	for i, k := range keys[:n] {
+		m.Put(k, i)
+	}
+	f := m.Freeze()
+	assert.Less(t, len(f.groups), m.ngroups)
+	// absent keys probe no more groups than in a full Map
+	var sum, max int
+	for _, k := range keys[n:] {
+		_, _, probed, ok := f.find(k)
+		require.False(t, ok)
+		sum += probed
+		if probed > max {
+			max = probed
+		}
+	}
+	stats := getProbeStats(t, m, keys[n:])
+	assert.LessOrEqual(t, float32(sum)/n, stats.absentAvg)
+	assert.LessOrEqual(t, uint32(max), stats.absentMax)
+	for _, r := range f.reach {
+		assert.Less(t, r, uint8(32))
+	}
+}
+
+func TestFrozenReach(t *testing.T) {
+	// every key is homed in the last group
+	last := func(k uint32, seed uint64) uint64 {
+		return math.MaxUint64&^h2Mask | uint64(k)&h2Mask
+	}
+	for _, n := range []int{100, 5000} {
+		m := NewMap[uint32, int](uint32(n))
+		for i := 0; i < n; i++ {
+			m.Put(uint32(i), i)
+		}
+		f := &FrozenMap[uint32, int]{count: n, hasher: keyHasher[uint32]{fn: last}}
+		f.ctrl, f.groups, f.reach = buildFrozen(m, f.hasher.hash)
+		home := len(f.groups) - 1
+		for g, r := range f.reach {
+			if g != home {
+				assert.Zero(t, r)
+			}
+		}
+		// elements wrap around to the first groups
+		assert.GreaterOrEqual(t, f.ctrl[0][0], int8(0))
+		if n > frozenUnbounded*groupSize {
+			assert.Equal(t, uint8(frozenUnbounded), f.reach[home])
+		} else {
+			assert.Equal(t, uint8(n/groupSize+1), f.reach[home])
+		}
+		for i := 0; i < n; i++ {
+			v, ok := f.Get(uint32(i))
+			assert.True(t, ok)
+			assert.Equal(t, i, v)
+		}
+		assert.False(t, f.Has(uint32(n)))
+	}
+}
@@ -0,0 +1,115 @@
+// Copyright 2023 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swiss
+
+import (
+	"fmt"
+)
+
+const (
+	maxGrowth    = 16
+	minCompactAt = 1.0 / 16
+)
+
+// GrowthPolicy decides how a Map's table is rebuilt once it is full,
+// that is once its elements and tombstones reach its maximum load.
+type GrowthPolicy interface {
+	// NextSize returns the number of groups to rebuild the table
+	// into. Returning |s.Groups| compacts the table in place to
+	// reclaim tombstones, fewer groups shrink it. The Map grows
+	// the result if it could not hold another element.
+	NextSize(s GrowthStats) (groups uint64)
+}
+
+// GrowthStats describes a full table.
+type GrowthStats struct {
+	// Groups is the number of groups in the table
+	Groups uint64
+	// Live is the number of elements in the table
+	Live uint64
+	// Tombstones is the number of deleted slots in the table
+	Tombstones uint64
+	// MaxLoad is the maximum average number of slots
+	// per group that may be filled before rebuilding
+	MaxLoad uint64
+}
+
+// Growth is a GrowthPolicy that grows the table geometrically,
+// compacting it instead if enough of its slots are tombstones.
+type Growth struct {
+	// Factor is the factor by which the table grows, in (1, 16].
+	Factor float64
+	// CompactAt is the fraction of filled slots, in [1/16, 1],
+	// that must be tombstones for the table to be compacted
+	// rather than grown.
+	CompactAt float64
+	// ShrinkBelow is the fraction of the maximum load below which
+	// a table being compacted is shrunk to half its maximum load.
+	// Zero disables shrinking, otherwise it must be less than the
+	// inverse of Factor, so that a grown table is not shrunk.
+	ShrinkBelow float64
+}
+
+// DefaultGrowth returns the policy of Maps constructed
+// without one, which doubles the table when it grows.
+func DefaultGrowth() Growth {
+	return Growth{Factor: 2, CompactAt: 0.5}
+}
+
+// ModestGrowth returns a policy that grows the table by half,
+// trading more frequent rebuilds for less unused memory.
+func ModestGrowth() Growth {
+	return Growth{Factor: 1.5, CompactAt: 0.5}
+}
+
+// EagerCompaction returns a policy for delete-heavy workloads, which
+// compacts the table rather than grow it once an eighth of its filled
+// slots are tombstones, and shrinks it if less than a quarter of its
+// maximum load is live.
+func EagerCompaction() Growth {
+	return Growth{Factor: 2, CompactAt: 0.125, ShrinkBelow: 0.25}
+}
+
+// defaultPolicy is shared by Maps constructed without a policy.
+var defaultPolicy GrowthPolicy = DefaultGrowth()
+
+// NextSize implements GrowthPolicy.
+func (g Growth) NextSize(s GrowthStats) (groups uint64) {
+	filled := s.Live + s.Tombstones
+	if s.Tombstones > 0 && s.Tombstones >= uint64(g.CompactAt*float64(filled)) {
+		if float64(s.Live) < g.ShrinkBelow*float64(s.Groups*s.MaxLoad) {
+			return numGroups(2*s.Live, s.MaxLoad)
+		}
+		return s.Groups
+	}
+	groups = uint64(float64(s.Groups) * g.Factor)
+	if groups <= s.Groups {
+		groups = s.Groups + 1
+	}
+	return
+}
+
+func (g Growth) validate() error {
+	if !(g.Factor > 1 && g.Factor <= maxGrowth) {
+		return fmt.Errorf("%w: growth factor %g", ErrInvalidOption, g.Factor)
+	}
+	if !(g.CompactAt >= minCompactAt && g.CompactAt <= 1) {
+		return fmt.Errorf("%w: compaction ratio %g", ErrInvalidOption, g.CompactAt)
+	}
+	if !(g.ShrinkBelow >= 0 && g.ShrinkBelow*g.Factor < 1) {
+		return fmt.Errorf("%w: shrink load %g for growth factor %g", ErrInvalidOption, g.ShrinkBelow, g.Factor)
+	}
+	return nil
+}
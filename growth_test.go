@@ -0,0 +1,214 @@
+// Copyright 2023 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swiss
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingPolicy records the cost of the rebuilds of a GrowthPolicy.
+type countingPolicy struct {
+	GrowthPolicy
+	rebuilds int
+	// moved counts the elements copied by rebuilds
+	moved uint64
+	// tombstones counts the tombstones reclaimed by rebuilds
+	tombstones uint64
+}
+
+func (p *countingPolicy) NextSize(s GrowthStats) uint64 {
+	p.rebuilds++
+	p.moved += s.Live
+	p.tombstones += s.Tombstones
+	return p.GrowthPolicy.NextSize(s)
+}
+
+var growthPolicies = []struct {
+	name   string
+	policy Growth
+}{
+	{"default", DefaultGrowth()},
+	{"modest", ModestGrowth()},
+	{"eager", EagerCompaction()},
+}
+
+func newCountingMap(t *testing.T, policy GrowthPolicy) (*Map[uint32, int], *countingPolicy) {
+	p := &countingPolicy{GrowthPolicy: policy}
+	m, err := New[uint32, int](WithGrowthPolicy(p))
+	require.NoError(t, err)
+	return m, p
+}
+
+func TestGrowthPolicyInserts(t *testing.T) {
+	const n = 200_000
+	// geometric growth by |f| copies each element at most f/(f-1)
+	// times on average, and leaves the table up to |f| times too large
+	bounds := map[string]struct{ moved, slack float64 }{
+		"default": {moved: 2.0, slack: 2.0},
+		"modest":  {moved: 3.0, slack: 1.5},
+		"eager":   {moved: 2.0, slack: 2.0},
+	}
+	for _, tc := range growthPolicies {
+		t.Run(tc.name, func(t *testing.T) {
+			m, p := newCountingMap(t, tc.policy)
+			for i := 0; i < n; i++ {
+				m.Put(uint32(i), i)
+			}
+			moved := float64(p.moved) / n
+			slack := float64(m.limit) / n
+			t.Logf("rebuilds: %d, moved per insert: %.3f, capacity per element: %.3f", p.rebuilds, moved, slack)
+			assert.LessOrEqual(t, moved, bounds[tc.name].moved)
+			assert.LessOrEqual(t, slack, bounds[tc.name].slack)
+			assert.Zero(t, p.tombstones)
+			assert.NoError(t, m.Validate())
+		})
+	}
+}
+
+func TestGrowthPolicyChurn(t *testing.T) {
+	// a sliding window of |live| keys: each op inserts
+	// a new key and deletes the oldest one
+	const live, ops = 10_000, 500_000
+	type result struct {
+		rebuilds, groups int
+	}
+	results := make(map[string]result)
+	for _, tc := range growthPolicies {
+		t.Run(tc.name, func(t *testing.T) {
+			m, p := newCountingMap(t, tc.policy)
+			for i := 0; i < live; i++ {
+				m.Put(uint32(i), i)
+			}
+			groups := m.ngroups
+			*p = countingPolicy{GrowthPolicy: p.GrowthPolicy}
+			for i := live; i < live+ops; i++ {
+				m.Put(uint32(i), i)
+				m.Delete(uint32(i - live))
+			}
+			assert.Equal(t, live, m.Count())
+			moved := float64(p.moved) / ops
+			t.Logf("rebuilds: %d, moved per op: %.3f, tombstones per rebuild: %.0f, groups: %d -> %d",
+				p.rebuilds, moved, float64(p.tombstones)/float64(p.rebuilds), groups, m.ngroups)
+			// each rebuild reclaims a fixed fraction of the
+			// table, so the cost per op is bounded
+			assert.Less(t, moved, 2.0)
+			// the table grows only until tombstones fill
+			// enough of it to compact, so it is bounded
+			assert.LessOrEqual(t, float64(m.ngroups), 2*tc.policy.Factor*float64(groups))
+			assert.NoError(t, m.Validate())
+			results[tc.name] = result{rebuilds: p.rebuilds, groups: m.ngroups}
+		})
+	}
+	// eager compaction rebuilds more often, reclaiming
+	// fewer tombstones each time, rather than growing
+	eager, def := results["eager"], results["default"]
+	assert.Greater(t, eager.rebuilds, def.rebuilds)
+	assert.Less(t, eager.groups, def.groups)
+}
+
+func TestGrowthPolicyShrink(t *testing.T) {
+	for _, tc := range growthPolicies {
+		t.Run(tc.name, func(t *testing.T) {
+			m, _ := newCountingMap(t, tc.policy)
+			keys := genUint32Data(100_000)
+			for i, k := range keys {
+				m.Put(k, i)
+			}
+			groups := m.ngroups
+			// keep 1% of the keys, then churn
+			m.DeleteFunc(func(k uint32, v int) bool {
+				return v%100 != 0
+			})
+			for i := 0; i < 100_000; i++ {
+				m.Put(uint32(i)|1<<31, i)
+				m.Delete(uint32(i) | 1<<31)
+			}
+			assert.Equal(t, 1000, m.Count())
+			if tc.policy.ShrinkBelow > 0 {
+				assert.Less(t, m.ngroups, groups/10)
+			} else {
+				assert.Equal(t, groups, m.ngroups)
+			}
+			for i, k := range keys {
+				v, ok := m.Get(k)
+				assert.Equal(t, i%100 == 0, ok)
+				if ok {
+					assert.Equal(t, i, v)
+				}
+			}
+			assert.NoError(t, m.Validate())
+		})
+	}
+}
+
+func TestGrowthNextSize(t *testing.T) {
+	g := DefaultGrowth()
+	s := GrowthStats{Groups: 100, Live: 1400, MaxLoad: 14}
+	assert.Equal(t, uint64(200), g.NextSize(s))
+	s.Live, s.Tombstones = 700, 700
+	assert.Equal(t, uint64(100), g.NextSize(s))
+	s.Live, s.Tombstones = 701, 699
+	assert.Equal(t, uint64(200), g.NextSize(s))
+	// small tables always grow
+	assert.Equal(t, uint64(2), ModestGrowth().NextSize(GrowthStats{Groups: 1, Live: 14, MaxLoad: 14}))
+	assert.Equal(t, uint64(3), ModestGrowth().NextSize(GrowthStats{Groups: 2, Live: 28, MaxLoad: 14}))
+	// shrinks to half the maximum load
+	e := EagerCompaction()
+	s = GrowthStats{Groups: 100, Live: 100, Tombstones: 1300, MaxLoad: 14}
+	assert.Equal(t, uint64(15), e.NextSize(s))
+	s.Live, s.Tombstones = 350, 1050
+	assert.Equal(t, uint64(100), e.NextSize(s))
+
+	// a policy that never grows is overruled
+	m, err := New[uint32, int](WithGrowthPolicy(fixedPolicy{}))
+	require.NoError(t, err)
+	for i := 0; i < 1000; i++ {
+		m.Put(uint32(i), i)
+	}
+	assert.Equal(t, 1000, m.Count())
+	assert.NoError(t, m.Validate())
+}
+
+type fixedPolicy struct{}
+
+func (fixedPolicy) NextSize(s GrowthStats) uint64 {
+	return s.Groups
+}
+
+func TestGrowthValidate(t *testing.T) {
+	for _, g := range growthPolicies {
+		assert.NoError(t, g.policy.validate())
+	}
+	invalid := []Growth{
+		{},
+		{Factor: 1, CompactAt: 0.5},
+		{Factor: maxGrowth * 2, CompactAt: 0.5},
+		{Factor: 2, CompactAt: 0},
+		{Factor: 2, CompactAt: 1.5},
+		{Factor: 2, CompactAt: 0.5, ShrinkBelow: 0.5},
+		{Factor: 2, CompactAt: 0.5, ShrinkBelow: -1},
+	}
+	for _, g := range invalid {
+		_, err := New[int, int](WithGrowthPolicy(g))
+		assert.ErrorIs(t, err, ErrInvalidOption)
+	}
+	_, err := New[int, int](WithGrowthPolicy(nil))
+	assert.ErrorIs(t, err, ErrInvalidOption)
+	_, err = New[int, int](WithGrowthPolicy(ModestGrowth()), WithGrowthFactor(3))
+	assert.ErrorIs(t, err, ErrInvalidOption)
+}
@@ -0,0 +1,47 @@
+// Copyright 2023 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build swissdebug
+
+package swiss
+
+import (
+	"sync/atomic"
+)
+
+// writeGuard detects concurrent misuse of a Map, like the
+// runtime's map does: writes set a flag that concurrent
+// reads and writes check. Races are detected on a best
+// effort basis, and always indicate a bug.
+type writeGuard struct {
+	writing int32
+}
+
+func (w *writeGuard) beginWrite() {
+	if !atomic.CompareAndSwapInt32(&w.writing, 0, 1) {
+		panic("swiss: concurrent map writes")
+	}
+}
+
+func (w *writeGuard) endWrite() {
+	if !atomic.CompareAndSwapInt32(&w.writing, 1, 0) {
+		panic("swiss: concurrent map writes")
+	}
+}
+
+func (w *writeGuard) checkRead() {
+	if atomic.LoadInt32(&w.writing) != 0 {
+		panic("swiss: concurrent map read and map write")
+	}
+}
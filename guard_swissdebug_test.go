@@ -0,0 +1,96 @@
+// Copyright 2023 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build swissdebug
+
+package swiss
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteGuard(t *testing.T) {
+	t.Run("single goroutine", func(t *testing.T) {
+		// reentrant use from one goroutine is not misuse
+		m := NewMap[uint32, int](0)
+		keys := genUint32Data(1000)
+		for i, k := range keys {
+			m.Put(k, i)
+		}
+		m.Iter(func(k uint32, v int) (stop bool) {
+			m.Put(k, -v)
+			return
+		})
+		m.DeleteFunc(func(k uint32, v int) bool {
+			return m.Has(k) && v%2 == 0
+		})
+		m.Merge(m.Fork(), func(k uint32, a, b int) int {
+			_, ok := m.Get(k)
+			assert.True(t, ok)
+			return a + b
+		})
+		m.Clear()
+	})
+	t.Run("concurrent writes", func(t *testing.T) {
+		assert.Equal(t, "swiss: concurrent map writes", racePanic(func(m *Map[uint32, int], i int) {
+			m.Put(uint32(i), i)
+		}))
+	})
+	t.Run("concurrent read and write", func(t *testing.T) {
+		assert.Contains(t, racePanic(func(m *Map[uint32, int], i int) {
+			if i%2 == 0 {
+				m.Put(uint32(i), i)
+			} else {
+				m.Get(uint32(i))
+			}
+		}), "swiss: concurrent map")
+	})
+}
+
+// racePanic calls |op| from several goroutines on a shared
+// Map until one of them panics, and returns the panic message.
+func racePanic(op func(m *Map[uint32, int], i int)) (msg string) {
+	m := NewMap[uint32, int](0)
+	var once sync.Once
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			defer func() {
+				if r := recover(); r != nil {
+					once.Do(func() {
+						msg = fmt.Sprint(r)
+						close(stop)
+					})
+				}
+			}()
+			for i := w; ; i += 8 {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				op(m, i%(1<<16))
+			}
+		}(w)
+	}
+	wg.Wait()
+	return
+}
@@ -0,0 +1,65 @@
+// Copyright 2023 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swiss
+
+import (
+	"time"
+)
+
+// Hooks are callbacks invoked as a Map's table is rebuilt or cleared,
+// for use in metrics and tracing. Any of the callbacks may be nil.
+// Callbacks are invoked synchronously and must not mutate the Map.
+type Hooks struct {
+	// OnGrow is called after the table is resized
+	// from |oldGroups| to |newGroups| groups.
+	OnGrow func(oldGroups, newGroups int, dur time.Duration)
+	// OnCompact is called after the table is rebuilt at
+	// the same size to reclaim |tombstones| deleted slots.
+	OnCompact func(groups, tombstones int, dur time.Duration)
+	// OnClear is called after the table's |groups| are cleared.
+	OnClear func(groups int, dur time.Duration)
+	// OnReseed is called after the table is rebuilt at the same
+	// size with a new hash seed, to break up long probe sequences.
+	OnReseed func(groups int, dur time.Duration)
+}
+
+// NewMapWithHooks constructs a Map that invokes |hooks|.
+func NewMapWithHooks[K comparable, V any](sz uint32, hooks Hooks) (m *Map[K, V]) {
+	m = NewMap[K, V](sz)
+	m.hooks = &hooks
+	return
+}
+
+func (h *Hooks) rehashed(oldGroups, newGroups, tombstones int, start time.Time) {
+	if oldGroups == newGroups {
+		if h.OnCompact != nil {
+			h.OnCompact(newGroups, tombstones, time.Since(start))
+		}
+	} else if h.OnGrow != nil {
+		h.OnGrow(oldGroups, newGroups, time.Since(start))
+	}
+}
+
+func (h *Hooks) reseeded(groups int, start time.Time) {
+	if h.OnReseed != nil {
+		h.OnReseed(groups, time.Since(start))
+	}
+}
+
+func (h *Hooks) cleared(groups int, start time.Time) {
+	if h.OnClear != nil {
+		h.OnClear(groups, time.Since(start))
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright 2023 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swiss
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHooks(t *testing.T) {
+	var grows, compacts, clears, reseeds int
+	var last int
+	hooks := Hooks{
+		OnGrow: func(oldGroups, newGroups int, dur time.Duration) {
+			assert.Greater(t, newGroups, oldGroups)
+			assert.Equal(t, last, oldGroups)
+			assert.GreaterOrEqual(t, dur, time.Duration(0))
+			last = newGroups
+			grows++
+		},
+		OnCompact: func(groups, tombstones int, dur time.Duration) {
+			assert.Equal(t, last, groups)
+			assert.Greater(t, tombstones, 0)
+			compacts++
+		},
+		OnClear: func(groups int, dur time.Duration) {
+			assert.Equal(t, last, groups)
+			clears++
+		},
+		OnReseed: func(groups int, dur time.Duration) {
+			assert.Equal(t, last, groups)
+			reseeds++
+		},
+	}
+	m := NewMapWithHooks[uint32, int](0, hooks)
+	last = m.ngroups
+	keys := genUint32Data(700 * maxAvgGroupLoad)
+	for i, k := range keys {
+		m.Put(k, i)
+	}
+	assert.Greater(t, grows, 0)
+	assert.Equal(t, last, m.ngroups)
+	m.Clear()
+	assert.Equal(t, 1, clears)
+
+	// fill to the maximum load factor, so that some
+	// groups are full and deletes leave tombstones
+	m = NewMapWithHooks[uint32, int](uint32(len(keys)), hooks)
+	last = m.ngroups
+	for i, k := range keys {
+		m.Put(k, i)
+	}
+	assert.Equal(t, 700, m.ngroups)
+	m.DeleteFunc(func(k uint32, v int) bool {
+		return true
+	})
+	assert.Equal(t, 1, compacts)
+
+	// reseeds are not reported as compactions
+	m.reseed()
+	assert.Equal(t, 1, reseeds)
+	assert.Equal(t, 1, compacts)
+	m.reseed()
+	assert.Equal(t, 1, reseeds)
+
+	// hooks are optional
+	m = NewMapWithHooks[uint32, int](0, Hooks{})
+	for i, k := range keys {
+		m.Put(k, i)
+	}
+	m.Clear()
+}
@@ -0,0 +1,136 @@
+// Copyright 2023 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swiss
+
+// slabChunk is the number of values in each chunk of a slab.
+const slabChunk = 256
+
+// IndirectMap is a hash map for large values. Values are stored out
+// of line in a slab and the table holds their indices, so growing the
+// table copies 4 bytes per value rather than the whole value, and a
+// value's address is stable until its key is deleted.
+type IndirectMap[K comparable, V any] struct {
+	index *Map[K, uint32]
+	slab  slab[V]
+}
+
+// slab is a dense store of values allocated in fixed size chunks,
+// which are never moved. Freed slots are recycled through a free list.
+type slab[V any] struct {
+	chunks []*[slabChunk]V
+	len    uint32
+	free   []uint32
+}
+
+// NewIndirectMap constructs an IndirectMap.
+func NewIndirectMap[K comparable, V any](sz uint32) *IndirectMap[K, V] {
+	return &IndirectMap[K, V]{index: NewMap[K, uint32](sz)}
+}
+
+// Has returns true if |key| is present in |m|.
+func (m *IndirectMap[K, V]) Has(key K) bool {
+	return m.index.Has(key)
+}
+
+// Get returns the |value| mapped by |key| if one exists.
+func (m *IndirectMap[K, V]) Get(key K) (value V, ok bool) {
+	var i uint32
+	if i, ok = m.index.Get(key); ok {
+		value = *m.slab.at(i)
+	}
+	return
+}
+
+// Ptr returns a pointer to the value mapped by |key|, or nil if
+// |key| is absent. The pointer is valid until |key| is deleted.
+func (m *IndirectMap[K, V]) Ptr(key K) (value *V) {
+	if i, ok := m.index.Get(key); ok {
+		value = m.slab.at(i)
+	}
+	return
+}
+
+// Put attempts to insert |key| and |value|. Existing
+// values are updated in place.
+func (m *IndirectMap[K, V]) Put(key K, value V) {
+	i, ok := m.index.upsert(key)
+	if !ok {
+		*i = m.slab.alloc()
+	}
+	*m.slab.at(*i) = value
+}
+
+// Delete attempts to remove |key|, returns true successful.
+func (m *IndirectMap[K, V]) Delete(key K) (ok bool) {
+	var i uint32
+	if i, ok = m.index.Get(key); ok {
+		m.index.Delete(key)
+		m.slab.release(i)
+	}
+	return
+}
+
+// Iter iterates the elements of the IndirectMap, passing them to
+// the callback. It makes the same guarantees as Map.Iter.
+func (m *IndirectMap[K, V]) Iter(cb func(k K, v V) (stop bool)) {
+	m.index.Iter(func(k K, i uint32) (stop bool) {
+		return cb(k, *m.slab.at(i))
+	})
+}
+
+// Clear removes all elements from the IndirectMap.
+func (m *IndirectMap[K, V]) Clear() {
+	m.index.Clear()
+	m.slab.clear()
+}
+
+// Count returns the number of elements in the IndirectMap.
+func (m *IndirectMap[K, V]) Count() int {
+	return m.index.Count()
+}
+
+func (s *slab[V]) at(i uint32) *V {
+	return &s.chunks[i/slabChunk][i%slabChunk]
+}
+
+// alloc returns the index of an unused, zeroed slot.
+func (s *slab[V]) alloc() (i uint32) {
+	if n := len(s.free); n > 0 {
+		i = s.free[n-1]
+		s.free = s.free[:n-1]
+		return
+	}
+	if s.len == uint32(len(s.chunks))*slabChunk {
+		s.chunks = append(s.chunks, new([slabChunk]V))
+	}
+	i = s.len
+	s.len++
+	return
+}
+
+func (s *slab[V]) release(i uint32) {
+	var zero V
+	*s.at(i) = zero
+	s.free = append(s.free, i)
+}
+
+// clear zeros the slab, keeping its chunks for reuse.
+func (s *slab[V]) clear() {
+	for c := uint32(0); c*slabChunk < s.len; c++ {
+		*s.chunks[c] = [slabChunk]V{}
+	}
+	s.len = 0
+	s.free = s.free[:0]
+}
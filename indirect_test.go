@@ -0,0 +1,104 @@
+// Copyright 2023 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swiss
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type largeValue struct {
+	n   int
+	pad [31]int64
+}
+
+func TestIndirectMap(t *testing.T) {
+	t.Run("uint32=1000", func(t *testing.T) {
+		testIndirectMap(t, genUint32Data(1000))
+	})
+	t.Run("strings=10_000", func(t *testing.T) {
+		testIndirectMap(t, genStringData(16, 10_000))
+	})
+	t.Run("uint32=100_000", func(t *testing.T) {
+		testIndirectMap(t, genUint32Data(100_000))
+	})
+}
+
+func testIndirectMap[K comparable](t *testing.T, keys []K) {
+	m := NewIndirectMap[K, largeValue](0)
+	for i, k := range keys {
+		assert.Nil(t, m.Ptr(k))
+		m.Put(k, largeValue{n: i})
+		assert.Equal(t, i+1, m.Count())
+	}
+	for i, k := range keys {
+		v, ok := m.Get(k)
+		assert.True(t, ok)
+		assert.Equal(t, i, v.n)
+		assert.True(t, m.Has(k))
+	}
+
+	// values do not move as the table grows
+	ptrs := make([]*largeValue, len(keys))
+	for i, k := range keys {
+		ptrs[i] = m.Ptr(k)
+		require.NotNil(t, ptrs[i])
+	}
+	m.index.rehash(uint64(m.index.ngroups) * 4)
+	for i, k := range keys {
+		assert.Same(t, ptrs[i], m.Ptr(k))
+		assert.Equal(t, i, ptrs[i].n)
+	}
+	// updates are made in place
+	m.Put(keys[0], largeValue{n: -1})
+	assert.Equal(t, -1, ptrs[0].n)
+	m.Put(keys[0], largeValue{n: 0})
+
+	// deleted slots are zeroed and reused
+	half := keys[:len(keys)/2]
+	for _, k := range half {
+		assert.True(t, m.Delete(k))
+		assert.False(t, m.Delete(k))
+	}
+	assert.Equal(t, len(keys)-len(half), m.Count())
+	for i := range half {
+		assert.Equal(t, largeValue{}, *ptrs[i])
+	}
+	slots := m.slab.len
+	for i, k := range half {
+		m.Put(k, largeValue{n: i})
+	}
+	assert.Equal(t, slots, m.slab.len)
+	assert.Empty(t, m.slab.free)
+
+	n := 0
+	m.Iter(func(k K, v largeValue) (stop bool) {
+		assert.Equal(t, k, keys[v.n])
+		n++
+		return
+	})
+	assert.Equal(t, len(keys), n)
+
+	m.Clear()
+	assert.Equal(t, 0, m.Count())
+	for i, k := range keys {
+		assert.False(t, m.Has(k))
+		assert.Equal(t, largeValue{}, *ptrs[i])
+	}
+	m.Put(keys[0], largeValue{n: 7})
+	assert.Same(t, ptrs[0], m.Ptr(keys[0]))
+}
@@ -0,0 +1,145 @@
+// Copyright 2023 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swiss
+
+// nilEntry is the null index of an entryList.
+const nilEntry = ^uint32(0)
+
+// entryList is a doubly linked list of key-value pairs.
+// entries are stored in a dense slab and linked by index,
+// removed entries are recycled through a free list.
+type entryList[K comparable, V any] struct {
+	entries    []entry[K, V]
+	head, tail uint32
+	free       uint32
+	count      uint32
+}
+
+type entry[K comparable, V any] struct {
+	key        K
+	value      V
+	prev, next uint32
+}
+
+func newEntryList[K comparable, V any](sz uint32) entryList[K, V] {
+	return entryList[K, V]{
+		entries: make([]entry[K, V], 0, sz),
+		head:    nilEntry,
+		tail:    nilEntry,
+		free:    nilEntry,
+	}
+}
+
+// alloc returns the index of an unlinked entry holding |key| and |value|.
+func (l *entryList[K, V]) alloc(key K, value V) (i uint32) {
+	if l.free != nilEntry {
+		i = l.free
+		l.free = l.entries[i].next
+		l.entries[i] = entry[K, V]{key: key, value: value}
+	} else {
+		i = uint32(len(l.entries))
+		l.entries = append(l.entries, entry[K, V]{key: key, value: value})
+	}
+	l.count++
+	return
+}
+
+// pushBack appends a new entry to the back of the list.
+func (l *entryList[K, V]) pushBack(key K, value V) (i uint32) {
+	i = l.alloc(key, value)
+	l.linkBack(i)
+	return
+}
+
+// pushFront prepends a new entry to the front of the list.
+func (l *entryList[K, V]) pushFront(key K, value V) (i uint32) {
+	i = l.alloc(key, value)
+	l.linkFront(i)
+	return
+}
+
+// remove unlinks entry |i| and recycles it.
+func (l *entryList[K, V]) remove(i uint32) {
+	l.unlink(i)
+	l.entries[i] = entry[K, V]{prev: nilEntry, next: l.free}
+	l.free = i
+	l.count--
+}
+
+// moveToBack moves entry |i| to the back of the list.
+func (l *entryList[K, V]) moveToBack(i uint32) {
+	if l.tail == i {
+		return
+	}
+	l.unlink(i)
+	l.linkBack(i)
+}
+
+// moveToFront moves entry |i| to the front of the list.
+func (l *entryList[K, V]) moveToFront(i uint32) {
+	if l.head == i {
+		return
+	}
+	l.unlink(i)
+	l.linkFront(i)
+}
+
+func (l *entryList[K, V]) linkBack(i uint32) {
+	e := &l.entries[i]
+	e.prev, e.next = l.tail, nilEntry
+	if l.tail != nilEntry {
+		l.entries[l.tail].next = i
+	} else {
+		l.head = i
+	}
+	l.tail = i
+}
+
+func (l *entryList[K, V]) linkFront(i uint32) {
+	e := &l.entries[i]
+	e.prev, e.next = nilEntry, l.head
+	if l.head != nilEntry {
+		l.entries[l.head].prev = i
+	} else {
+		l.tail = i
+	}
+	l.head = i
+}
+
+func (l *entryList[K, V]) unlink(i uint32) {
+	e := &l.entries[i]
+	if e.prev != nilEntry {
+		l.entries[e.prev].next = e.next
+	} else {
+		l.head = e.next
+	}
+	if e.next != nilEntry {
+		l.entries[e.next].prev = e.prev
+	} else {
+		l.tail = e.prev
+	}
+	e.prev, e.next = nilEntry, nilEntry
+}
+
+// clear removes all entries, retaining the slab's capacity.
+func (l *entryList[K, V]) clear() {
+	var e entry[K, V]
+	for i := range l.entries {
+		l.entries[i] = e
+	}
+	l.entries = l.entries[:0]
+	l.head, l.tail, l.free = nilEntry, nilEntry, nilEntry
+	l.count = 0
+}
@@ -0,0 +1,144 @@
+// Copyright 2023 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swiss
+
+// LRU is a fixed-capacity cache that evicts the least recently
+// used element. Keys are indexed by a Map, entries are kept in a
+// dense slab linked in recency order, least recent first.
+type LRU[K comparable, V any] struct {
+	index   *Map[K, uint32]
+	list    entryList[K, V]
+	cap     uint32
+	onEvict func(k K, v V)
+	hits    uint64
+	misses  uint64
+}
+
+// NewLRU constructs an LRU holding at most |capacity| elements.
+// If |onEvict| is non-nil, it is called with each element evicted
+// to make room for a Put. It is not called by Remove or Clear.
+func NewLRU[K comparable, V any](capacity uint32, onEvict func(k K, v V)) *LRU[K, V] {
+	if capacity == 0 {
+		panic("swiss: LRU capacity must be positive")
+	}
+	return &LRU[K, V]{
+		index:   NewMap[K, uint32](capacity),
+		list:    newEntryList[K, V](capacity),
+		cap:     capacity,
+		onEvict: onEvict,
+	}
+}
+
+// Get returns the |value| mapped by |key| if one exists,
+// and marks |key| as most recently used.
+func (c *LRU[K, V]) Get(key K) (value V, ok bool) {
+	var i uint32
+	if i, ok = c.index.Get(key); ok {
+		c.hits++
+		c.list.moveToBack(i)
+		value = c.list.entries[i].value
+	} else {
+		c.misses++
+	}
+	return
+}
+
+// Peek returns the |value| mapped by |key| if one exists,
+// without updating recency or hit/miss counters.
+func (c *LRU[K, V]) Peek(key K) (value V, ok bool) {
+	var i uint32
+	if i, ok = c.index.Get(key); ok {
+		value = c.list.entries[i].value
+	}
+	return
+}
+
+// Has returns true if |key| is present in |c|, without
+// updating recency or hit/miss counters.
+func (c *LRU[K, V]) Has(key K) bool {
+	return c.index.Has(key)
+}
+
+// Put inserts or updates |key| and marks it as most recently
+// used, evicting the least recently used element if |c| is
+// full. Returns true if an element was evicted.
+func (c *LRU[K, V]) Put(key K, value V) (evicted bool) {
+	if i, ok := c.index.Get(key); ok {
+		c.list.entries[i].value = value
+		c.list.moveToBack(i)
+		return
+	}
+	if c.list.count >= c.cap {
+		lru := c.list.head
+		e := c.list.entries[lru]
+		c.index.Delete(e.key)
+		c.list.remove(lru)
+		if c.onEvict != nil {
+			c.onEvict(e.key, e.value)
+		}
+		evicted = true
+	}
+	c.index.Put(key, c.list.pushBack(key, value))
+	return
+}
+
+// Remove attempts to remove |key|, returns true successful.
+func (c *LRU[K, V]) Remove(key K) (ok bool) {
+	var i uint32
+	if i, ok = c.index.Get(key); ok {
+		c.index.Delete(key)
+		c.list.remove(i)
+	}
+	return
+}
+
+// Iter iterates the elements of the LRU from least to most
+// recently used, without updating recency.
+func (c *LRU[K, V]) Iter(cb func(k K, v V) (stop bool)) {
+	for i := c.list.head; i != nilEntry; {
+		e := c.list.entries[i]
+		i = e.next
+		if stop := cb(e.key, e.value); stop {
+			return
+		}
+	}
+}
+
+// Clear removes all elements from the LRU and resets its counters.
+func (c *LRU[K, V]) Clear() {
+	c.index.Clear()
+	c.list.clear()
+	c.hits, c.misses = 0, 0
+}
+
+// Count returns the number of elements in the LRU.
+func (c *LRU[K, V]) Count() int {
+	return int(c.list.count)
+}
+
+// Capacity returns the maximum number of elements in the LRU.
+func (c *LRU[K, V]) Capacity() int {
+	return int(c.cap)
+}
+
+// Hits returns the number of Get calls that found their key.
+func (c *LRU[K, V]) Hits() uint64 {
+	return c.hits
+}
+
+// Misses returns the number of Get calls that did not find their key.
+func (c *LRU[K, V]) Misses() uint64 {
+	return c.misses
+}
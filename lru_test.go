@@ -0,0 +1,109 @@
+// Copyright 2023 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swiss
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLRU(t *testing.T) {
+	t.Run("uint32=1000", func(t *testing.T) {
+		testLRU(t, genUint32Data(1000))
+	})
+	t.Run("strings=10_000", func(t *testing.T) {
+		testLRU(t, genStringData(16, 10_000))
+	})
+}
+
+func testLRU[K comparable](t *testing.T, keys []K) {
+	capacity := len(keys) / 4
+	t.Run("evict", func(t *testing.T) {
+		var evicted []K
+		c := NewLRU[K, int](uint32(capacity), func(k K, v int) {
+			evicted = append(evicted, k)
+		})
+		for i, k := range keys {
+			assert.Equal(t, i >= capacity, c.Put(k, i))
+			assert.LessOrEqual(t, c.Count(), capacity)
+		}
+		assert.Equal(t, capacity, c.Count())
+		// evicted in insertion order
+		assert.Equal(t, keys[:len(keys)-capacity], evicted)
+		for i, k := range keys {
+			v, ok := c.Peek(k)
+			assert.Equal(t, i >= len(keys)-capacity, ok)
+			if ok {
+				assert.Equal(t, i, v)
+			}
+		}
+		assert.Equal(t, uint64(0), c.Hits()+c.Misses())
+	})
+	t.Run("promote", func(t *testing.T) {
+		c := NewLRU[K, int](uint32(capacity), nil)
+		for i, k := range keys[:capacity] {
+			c.Put(k, i)
+		}
+		// promote the oldest element, then
+		// overflow by one, evicting keys[1]
+		_, ok := c.Get(keys[0])
+		assert.True(t, ok)
+		c.Put(keys[capacity], capacity)
+		assert.True(t, c.Has(keys[0]))
+		assert.False(t, c.Has(keys[1]))
+		_, ok = c.Get(keys[1])
+		assert.False(t, ok)
+		assert.Equal(t, uint64(1), c.Hits())
+		assert.Equal(t, uint64(1), c.Misses())
+		// Peek does not promote
+		_, ok = c.Peek(keys[2])
+		assert.True(t, ok)
+		c.Put(keys[capacity+1], capacity+1)
+		assert.False(t, c.Has(keys[2]))
+		// updates promote
+		c.Put(keys[3], -3)
+		c.Put(keys[capacity+2], capacity+2)
+		assert.True(t, c.Has(keys[3]))
+		assert.False(t, c.Has(keys[4]))
+		var last K
+		c.Iter(func(k K, v int) (stop bool) {
+			last = k
+			return
+		})
+		assert.Equal(t, keys[capacity+2], last)
+	})
+	t.Run("remove", func(t *testing.T) {
+		var evictions int
+		c := NewLRU[K, int](uint32(capacity), func(k K, v int) {
+			evictions++
+		})
+		for i, k := range keys[:capacity] {
+			c.Put(k, i)
+		}
+		for _, k := range keys[:capacity/2] {
+			assert.True(t, c.Remove(k))
+			assert.False(t, c.Remove(k))
+		}
+		assert.Equal(t, capacity-capacity/2, c.Count())
+		for i, k := range keys[capacity : capacity+capacity/2] {
+			assert.False(t, c.Put(k, i))
+		}
+		assert.Equal(t, 0, evictions)
+		c.Clear()
+		assert.Equal(t, 0, c.Count())
+		assert.Equal(t, capacity, c.Capacity())
+	})
+}
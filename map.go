@@ -15,6 +15,13 @@
 package swiss
 
 import (
+	"errors"
+	"math"
+	"math/bits"
+	"sync/atomic"
+	"time"
+	"unsafe"
+
 	"github.com/dolthub/maphash"
 )
 
@@ -22,15 +29,36 @@ const (
 	maxLoadFactor = float32(maxAvgGroupLoad) / float32(groupSize)
 )
 
+// ErrMemoryLimit is returned by TryPut if inserting
+// would grow the table beyond the Map's memory limit.
+var ErrMemoryLimit = errors.New("swiss: memory limit exceeded")
+
 // Map is an open-addressing hash map
 // based on Abseil's flat_hash_map.
 type Map[K comparable, V any] struct {
-	ctrl     []metadata
-	groups   []group[K, V]
-	hash     maphash.Hasher[K]
-	resident uint32
-	dead     uint32
-	limit    uint32
+	// guard is zero-sized unless built with -tags swissdebug
+	guard writeGuard
+	table[K, V]
+	hash     keyHasher[K]
+	resident uint64
+	dead     uint64
+	limit    uint64
+	// maxLoad is the maximum average number of elements per group
+	maxLoad uint64
+	// policy sizes the table when it is rebuilt
+	policy GrowthPolicy
+	// memLimit bounds the table size for TryPut, zero if unlimited
+	memLimit uint64
+	hooks    *Hooks
+	// fixedSeed is set if |hash| is shared with other Maps
+	fixedSeed bool
+	// pinned is set once callers may hold hashes computed
+	// with the seed of |hash|, it is accessed atomically
+	pinned uint32
+	// reseeded is set if the table was reseeded at its current size
+	reseeded bool
+	// seedGen counts reseeds
+	seedGen uint32
 }
 
 // metadata is the h2 metadata array for a group.
@@ -44,6 +72,34 @@ type group[K comparable, V any] struct {
 	values [groupSize]V
 }
 
+const (
+	tableChunkShift  = 8
+	tableChunkGroups = 1 << tableChunkShift
+	tableChunkMask   = tableChunkGroups - 1
+)
+
+// table holds the groups of a Map, in |ctrl| and |groups| unless
+// it was split into |chunks| to be shared with forks, see Map.Fork.
+type table[K comparable, V any] struct {
+	ctrl   []metadata
+	groups []group[K, V]
+	chunks []*tableChunk[K, V]
+	// ngroups is the number of groups in the table
+	ngroups int
+	// dirty has a bit set for each group written since the table
+	// was allocated or cleared, nil once the table is split
+	dirty []uint64
+}
+
+// tableChunk is a run of up to |tableChunkGroups| groups of a table.
+// Forks share chunks, and a Map copies a shared chunk before writing it.
+type tableChunk[K comparable, V any] struct {
+	ctrl   []metadata
+	groups []group[K, V]
+	// refs counts the Maps referencing the chunk
+	refs int32
+}
+
 const (
 	h1Mask    uint64 = 0xffff_ffff_ffff_ff80
 	h2Mask    uint64 = 0x0000_0000_0000_007f
@@ -57,43 +113,88 @@ type h1 uint64
 // h2 is a 7 bit hash suffix
 type h2 int8
 
-// NewMap constructs a Map.
+// NewMap constructs a Map with the default options, see New. The Map
+// may grow beyond the uint32 range of |sz|, to as many elements as
+// fit in memory.
 func NewMap[K comparable, V any](sz uint32) (m *Map[K, V]) {
-	groups := numGroups(sz)
+	return newMap[K, V](uint64(sz), maxAvgGroupLoad)
+}
+
+func newMap[K comparable, V any](sz, maxLoad uint64) (m *Map[K, V]) {
 	m = &Map[K, V]{
-		ctrl:   make([]metadata, groups),
-		groups: make([]group[K, V], groups),
-		hash:   maphash.NewHasher[K](),
-		limit:  groups * maxAvgGroupLoad,
+		hash:    keyHasher[K]{Hasher: maphash.NewHasher[K]()},
+		maxLoad: maxLoad,
+		policy:  defaultPolicy,
 	}
-	for i := range m.ctrl {
-		m.ctrl[i] = newEmptyMetadata()
+	m.alloc(numGroups(sz, maxLoad))
+	return
+}
+
+// NewMapWithHasher constructs a Map that hashes keys with |h|. Maps
+// constructed with the same Hasher compute the same hash for a key,
+// which may be computed once and passed to the *Hashed methods of
+// each Map. It panics if |h| is the zero Hasher.
+func NewMapWithHasher[K comparable, V any](sz uint32, h maphash.Hasher[K]) (m *Map[K, V]) {
+	if err := checkHasher(h); err != nil {
+		panic(err)
 	}
+	m = NewMap[K, V](sz)
+	m.hash = keyHasher[K]{Hasher: h}
+	m.fixedSeed = true
 	return
 }
 
+// Hasher returns the Hasher used by |m|. A Map's Hasher does not
+// change as it grows. A Map may otherwise pick a new seed to defend
+// against hash flooding, see Put, but once Hasher, Hash or a *Hashed
+// method is called its seed is pinned, so that hashes held by the
+// caller stay valid. A Map configured with WithHashFunc or WithSeed
+// does not hash keys with a Hasher, and returns the zero Hasher, which
+// NewMapWithHasher and WithHasher reject.
+func (m *Map[K, V]) Hasher() maphash.Hasher[K] {
+	m.pinSeed()
+	return m.hash.Hasher
+}
+
+// Hash returns the hash of |key| used by |m|, which may be passed to
+// the *Hashed methods. Like Hasher, it pins the seed of |m|.
+func (m *Map[K, V]) Hash(key K) uint64 {
+	m.pinSeed()
+	return m.hash.hash(key)
+}
+
+// pinSeed stops |m| from reseeding. It may
+// be called by concurrent readers.
+func (m *Map[K, V]) pinSeed() {
+	if atomic.LoadUint32(&m.pinned) == 0 {
+		atomic.StoreUint32(&m.pinned, 1)
+	}
+}
+
 // Has returns true if |key| is present in |m|.
 func (m *Map[K, V]) Has(key K) (ok bool) {
-	hi, lo := splitHash(m.hash.Hash(key))
-	g := probeStart(hi, len(m.groups))
+	m.guard.checkRead()
+	hi, lo := splitHash(m.hash.hash(key))
+	g := probeStart(hi, m.ngroups)
 	for { // inlined find loop
-		matches := metaMatchH2(&m.ctrl[g], lo)
+		ctrl, grp := m.at(g)
+		matches := metaMatchH2(ctrl, lo)
 		for matches != 0 {
 			s := nextMatch(&matches)
-			if key == m.groups[g].keys[s] {
+			if key == grp.keys[s] {
 				ok = true
 				return
 			}
 		}
 		// |key| is not in group |g|,
 		// stop probing if we see an empty slot
-		matches = metaMatchEmpty(&m.ctrl[g])
+		matches = metaMatchEmpty(ctrl)
 		if matches != 0 {
 			ok = false
 			return
 		}
 		g += 1 // linear probing
-		if g >= uint32(len(m.groups)) {
+		if g >= uint64(m.ngroups) {
 			g = 0
 		}
 	}
@@ -101,111 +202,370 @@ func (m *Map[K, V]) Has(key K) (ok bool) {
 
 // Get returns the |value| mapped by |key| if one exists.
 func (m *Map[K, V]) Get(key K) (value V, ok bool) {
-	hi, lo := splitHash(m.hash.Hash(key))
-	g := probeStart(hi, len(m.groups))
+	m.guard.checkRead()
+	hi, lo := splitHash(m.hash.hash(key))
+	g := probeStart(hi, m.ngroups)
 	for { // inlined find loop
-		matches := metaMatchH2(&m.ctrl[g], lo)
+		ctrl, grp := m.at(g)
+		matches := metaMatchH2(ctrl, lo)
 		for matches != 0 {
 			s := nextMatch(&matches)
-			if key == m.groups[g].keys[s] {
-				value, ok = m.groups[g].values[s], true
+			if key == grp.keys[s] {
+				value, ok = grp.values[s], true
 				return
 			}
 		}
 		// |key| is not in group |g|,
 		// stop probing if we see an empty slot
-		matches = metaMatchEmpty(&m.ctrl[g])
+		matches = metaMatchEmpty(ctrl)
 		if matches != 0 {
 			ok = false
 			return
 		}
 		g += 1 // linear probing
-		if g >= uint32(len(m.groups)) {
+		if g >= uint64(m.ngroups) {
 			g = 0
 		}
 	}
 }
 
-// Put attempts to insert |key| and |value|
+// Put attempts to insert |key| and |value|. If inserting requires an
+// abnormally long probe sequence, as happens when keys are chosen to
+// collide, the Map is rebuilt with a new hash seed.
 func (m *Map[K, V]) Put(key K, value V) {
+	m.guard.beginWrite()
 	if m.resident >= m.limit {
 		m.rehash(m.nextSize())
 	}
-	hi, lo := splitHash(m.hash.Hash(key))
-	g := probeStart(hi, len(m.groups))
+	hi, lo := splitHash(m.hash.hash(key))
+	g := probeStart(hi, m.ngroups)
 	for { // inlined find loop
-		matches := metaMatchH2(&m.ctrl[g], lo)
+		ctrl, grp := m.at(g)
+		matches := metaMatchH2(ctrl, lo)
 		for matches != 0 {
 			s := nextMatch(&matches)
-			if key == m.groups[g].keys[s] { // update
-				m.groups[g].keys[s] = key
-				m.groups[g].values[s] = value
+			if key == grp.keys[s] { // update
+				_, grp = m.mut(g)
+				grp.keys[s] = key
+				grp.values[s] = value
+				m.guard.endWrite()
 				return
 			}
 		}
 		// |key| is not in group |g|,
 		// stop probing if we see an empty slot
-		matches = metaMatchEmpty(&m.ctrl[g])
+		matches = metaMatchEmpty(ctrl)
 		if matches != 0 { // insert
 			s := nextMatch(&matches)
-			m.groups[g].keys[s] = key
-			m.groups[g].values[s] = value
-			m.ctrl[g][s] = int8(lo)
+			ctrl, grp = m.mut(g)
+			grp.keys[s] = key
+			grp.values[s] = value
+			ctrl[s] = int8(lo)
 			m.resident++
+			m.checkProbe(hi, g)
+			m.guard.endWrite()
 			return
 		}
 		g += 1 // linear probing
-		if g >= uint32(len(m.groups)) {
+		if g >= uint64(m.ngroups) {
 			g = 0
 		}
 	}
 }
 
+// HasHashed is like Has, for a |hash| of |key| computed by m.Hash.
+func (m *Map[K, V]) HasHashed(hash uint64, key K) (ok bool) {
+	m.guard.checkRead()
+	m.pinSeed()
+	hi, lo := splitHash(hash)
+	_, _, ok = m.find(key, hi, lo)
+	return
+}
+
+// GetHashed is like Get, for a |hash| of |key| computed by m.Hash.
+func (m *Map[K, V]) GetHashed(hash uint64, key K) (value V, ok bool) {
+	m.guard.checkRead()
+	m.pinSeed()
+	hi, lo := splitHash(hash)
+	g, s, ok := m.find(key, hi, lo)
+	if ok {
+		_, grp := m.at(g)
+		value = grp.values[s]
+	}
+	return
+}
+
+// PutHashed is like Put, for a |hash| of |key| computed by m.Hash.
+func (m *Map[K, V]) PutHashed(hash uint64, key K, value V) {
+	m.pinSeed()
+	v, _ := m.upsertHashed(hash, key)
+	*v = value
+}
+
+// DeleteHashed is like Delete, for a |hash| of |key| computed by m.Hash.
+func (m *Map[K, V]) DeleteHashed(hash uint64, key K) (ok bool) {
+	m.pinSeed()
+	hi, lo := splitHash(hash)
+	g, s, ok := m.find(key, hi, lo)
+	if ok {
+		m.deleteAt(g, s)
+	}
+	return
+}
+
+// upsert returns a pointer to the value mapped by |key|, inserting
+// a zero value if |key| is absent. |ok| reports whether |key| was
+// already present. The pointer is valid until |m| is next mutated.
+func (m *Map[K, V]) upsert(key K) (value *V, ok bool) {
+	return m.upsertHashed(m.hash.hash(key), key)
+}
+
+func (m *Map[K, V]) upsertHashed(hash uint64, key K) (value *V, ok bool) {
+	m.guard.beginWrite()
+	if m.resident >= m.limit {
+		m.rehash(m.nextSize())
+	}
+	hi, lo := splitHash(hash)
+	g, s, ok := m.find(key, hi, lo)
+	ctrl, grp := m.mut(g)
+	if !ok {
+		grp.keys[s] = key
+		ctrl[s] = int8(lo)
+		m.resident++
+		gen := m.seedGen
+		if m.checkProbe(hi, g); gen != m.seedGen {
+			// reseeding moved the key
+			hi, lo = splitHash(m.hash.hash(key))
+			g, s, _ = m.find(key, hi, lo)
+			_, grp = m.mut(g)
+		}
+	}
+	value = &grp.values[s]
+	m.guard.endWrite()
+	return
+}
+
+// TryPut is like Put, but returns ErrMemoryLimit rather than
+// growing the table beyond the limit set by SetMemoryLimit.
+func (m *Map[K, V]) TryPut(key K, value V) error {
+	if m.resident >= m.limit && m.memLimit != 0 {
+		hi, lo := splitHash(m.hash.hash(key))
+		if g, s, ok := m.find(key, hi, lo); ok { // update
+			m.guard.beginWrite()
+			_, grp := m.mut(g)
+			grp.values[s] = value
+			m.guard.endWrite()
+			return nil
+		}
+		if tableSize[K, V](m.nextSize()) > m.memLimit {
+			return ErrMemoryLimit
+		}
+	}
+	m.Put(key, value)
+	return nil
+}
+
 // Delete attempts to remove |key|, returns true successful.
 func (m *Map[K, V]) Delete(key K) (ok bool) {
-	hi, lo := splitHash(m.hash.Hash(key))
-	g := probeStart(hi, len(m.groups))
+	hi, lo := splitHash(m.hash.hash(key))
+	g := probeStart(hi, m.ngroups)
 	for {
-		matches := metaMatchH2(&m.ctrl[g], lo)
+		ctrl, grp := m.at(g)
+		matches := metaMatchH2(ctrl, lo)
 		for matches != 0 {
 			s := nextMatch(&matches)
-			if key == m.groups[g].keys[s] {
+			if key == grp.keys[s] {
 				ok = true
-				// optimization: if |m.ctrl[g]| contains any empty
-				// metadata bytes, we can physically delete |key|
-				// rather than placing a tombstone.
-				// The observation is that any probes into group |g|
-				// would already be terminated by the existing empty
-				// slot, and therefore reclaiming slot |s| will not
-				// cause premature termination of probes into |g|.
-				if metaMatchEmpty(&m.ctrl[g]) != 0 {
-					m.ctrl[g][s] = empty
-					m.resident--
-				} else {
-					m.ctrl[g][s] = tombstone
-					m.dead++
-				}
-				var k K
-				var v V
-				m.groups[g].keys[s] = k
-				m.groups[g].values[s] = v
+				m.deleteAt(g, s)
 				return
 			}
 		}
 		// |key| is not in group |g|,
 		// stop probing if we see an empty slot
-		matches = metaMatchEmpty(&m.ctrl[g])
+		matches = metaMatchEmpty(ctrl)
 		if matches != 0 { // |key| absent
 			ok = false
 			return
 		}
 		g += 1 // linear probing
-		if g >= uint32(len(m.groups)) {
+		if g >= uint64(m.ngroups) {
 			g = 0
 		}
 	}
 }
 
+// DeleteFunc removes every element for which |del| returns true
+// in a single pass over the table, and returns the number of
+// elements removed.
+func (m *Map[K, V]) DeleteFunc(del func(k K, v V) bool) (n int) {
+	for g := uint64(0); g < uint64(m.ngroups); g++ {
+		ctrl, grp := m.at(g)
+		for s, c := range ctrl {
+			if c == empty || c == tombstone {
+				continue
+			}
+			if !del(grp.keys[s], grp.values[s]) {
+				continue
+			}
+			m.deleteAt(g, uint32(s))
+			n++
+		}
+	}
+	// bulk deletes can leave many tombstones behind,
+	// compact the table rather than waiting for Put
+	if m.dead > 0 && m.dead >= m.resident/2 {
+		m.guard.beginWrite()
+		// compact or shrink, but never grow
+		groups := m.nextSize()
+		if groups > uint64(m.ngroups) {
+			groups = uint64(m.ngroups)
+		}
+		m.rehash(groups)
+		m.guard.endWrite()
+	}
+	return
+}
+
+// deleteAt removes the element in slot |s| of group |g|.
+func (m *Map[K, V]) deleteAt(g uint64, s uint32) {
+	m.guard.beginWrite()
+	ctrl, grp := m.mut(g)
+	// optimization: if |ctrl| contains any empty
+	// metadata bytes, we can physically delete |key|
+	// rather than placing a tombstone.
+	// The observation is that any probes into group |g|
+	// would already be terminated by the existing empty
+	// slot, and therefore reclaiming slot |s| will not
+	// cause premature termination of probes into |g|.
+	if metaMatchEmpty(ctrl) != 0 {
+		ctrl[s] = empty
+		m.resident--
+	} else {
+		ctrl[s] = tombstone
+		m.dead++
+	}
+	var k K
+	var v V
+	grp.keys[s] = k
+	grp.values[s] = v
+	m.guard.endWrite()
+}
+
+// Merge inserts every element of |other| into |m|. For keys present
+// in both Maps, the value stored is the result of |resolve| applied
+// to the value in |m| and the value in |other|.
+func (m *Map[K, V]) Merge(other *Map[K, V], resolve func(k K, a, b V) V) {
+	// |resolve| may read |m|, so the write guard
+	// is only held while |m| is modified
+	if m == other {
+		for g := uint64(0); g < uint64(m.ngroups); g++ {
+			ctrl, grp := m.at(g)
+			for s, c := range ctrl {
+				if c == empty || c == tombstone {
+					continue
+				}
+				k, v := grp.keys[s], grp.values[s]
+				v = resolve(k, v, v)
+				m.guard.beginWrite()
+				_, w := m.mut(g)
+				w.values[s] = v
+				m.guard.endWrite()
+			}
+		}
+		return
+	}
+	// pre-size |m| for the worst case (disjoint key sets)
+	// so that no rehash happens mid-merge
+	if m.resident+uint64(other.Count()) > m.limit {
+		m.guard.beginWrite()
+		m.rehash(numGroups(uint64(m.Count()+other.Count()), m.maxLoad))
+		m.guard.endWrite()
+	}
+	// walk |other|'s table directly: each key is hashed exactly
+	// once, to locate it in |m|, and then updated or inserted in
+	// place without re-probing
+	for g := uint64(0); g < uint64(other.ngroups); g++ {
+		ctrl, grp := other.at(g)
+		for s, c := range ctrl {
+			if c == empty || c == tombstone {
+				continue
+			}
+			k, b := grp.keys[s], grp.values[s]
+			hi, lo := splitHash(m.hash.hash(k))
+			mg, ms, ok := m.find(k, hi, lo)
+			if ok {
+				_, dst := m.at(mg)
+				v := resolve(k, dst.values[ms], b)
+				m.guard.beginWrite()
+				_, dst = m.mut(mg)
+				dst.values[ms] = v
+				m.guard.endWrite()
+				continue
+			}
+			m.guard.beginWrite()
+			mc, dst := m.mut(mg)
+			dst.keys[ms] = k
+			dst.values[ms] = b
+			mc[ms] = int8(lo)
+			m.resident++
+			m.checkProbe(hi, mg)
+			m.guard.endWrite()
+		}
+	}
+}
+
+// IntersectWith removes every element of |m| whose key is not present in |other|.
+func (m *Map[K, V]) IntersectWith(other *Map[K, V]) {
+	if m == other {
+		return
+	}
+	m.DeleteFunc(func(k K, _ V) bool {
+		return !other.Has(k)
+	})
+}
+
+// Subtract removes every element of |m| whose key is present in |other|.
+func (m *Map[K, V]) Subtract(other *Map[K, V]) {
+	if m == other {
+		m.Clear()
+		return
+	}
+	if other.Count() < m.Count() {
+		// cheaper to probe |m| once per key of |other|
+		other.Iter(func(k K, _ V) (stop bool) {
+			m.Delete(k)
+			return
+		})
+		return
+	}
+	m.DeleteFunc(func(k K, _ V) bool {
+		return other.Has(k)
+	})
+}
+
+// Fork returns a copy of |m|. The copy shares |m|'s table, in chunks
+// of up to 256 groups, and either Map copies a chunk the first time it
+// writes to it. Fork is O(chunks), making it cheap to hand a consistent
+// snapshot of |m| to concurrent readers while the owner continues to
+// write. The chunks of a table are garbage collected together, once no
+// fork references any of them.
+//
+// A fork that is dropped without being mutated still counts as a
+// reference, so the next write to each chunk of |m| copies it. Forked
+// Maps reach their groups through their chunks until they next rehash
+// or are cleared.
+func (m *Map[K, V]) Fork() *Map[K, V] {
+	if m.chunks == nil {
+		m.split()
+	}
+	for _, c := range m.chunks {
+		atomic.AddInt32(&c.refs, 1)
+	}
+	f := *m
+	f.chunks = append([]*tableChunk[K, V](nil), m.chunks...)
+	return &f
+}
+
 // Iter iterates the elements of the Map, passing them to the callback.
 // It guarantees that any key in the Map will be visited only once, and
 // for un-mutated Maps, every key will be visited once. If the Map is
@@ -214,43 +574,93 @@ func (m *Map[K, V]) Delete(key K) (ok bool) {
 func (m *Map[K, V]) Iter(cb func(k K, v V) (stop bool)) {
 	// take a consistent view of the table in case
 	// we rehash during iteration
-	ctrl, groups := m.ctrl, m.groups
+	t, n := m.table, uint64(m.ngroups)
 	// pick a random starting group
-	g := randIntN(len(groups))
-	for n := 0; n < len(groups); n++ {
-		for s, c := range ctrl[g] {
+	g := randIntN(int(n))
+	for i := uint64(0); i < n; i++ {
+		ctrl, grp := t.at(g)
+		for s, c := range ctrl {
 			if c == empty || c == tombstone {
 				continue
 			}
-			k, v := groups[g].keys[s], groups[g].values[s]
+			m.guard.checkRead()
+			k, v := grp.keys[s], grp.values[s]
 			if stop := cb(k, v); stop {
 				return
 			}
 		}
 		g++
-		if g >= uint32(len(groups)) {
+		if g >= n {
 			g = 0
 		}
 	}
 }
 
-// Clear removes all elements from the Map.
+// Clear removes all elements from the Map. Only groups written since
+// the Map was last cleared are reset, so clearing a Map costs time
+// proportional to the number of groups it used, plus a bit per group,
+// rather than to its capacity. A forked Map is given a new table.
 func (m *Map[K, V]) Clear() {
-	for i, c := range m.ctrl {
-		for j := range c {
-			m.ctrl[i][j] = empty
-		}
+	if m.hooks != nil {
+		defer m.hooks.cleared(m.ngroups, time.Now())
 	}
-	var k K
-	var v V
-	for i := range m.groups {
-		g := &m.groups[i]
-		for i := range g.keys {
-			g.keys[i] = k
-			g.values[i] = v
+	m.guard.beginWrite()
+	if m.chunks != nil {
+		// cheaper to start over than to copy shared chunks
+		m.release()
+		m.alloc(uint64(m.ngroups))
+		m.guard.endWrite()
+		return
+	}
+	if m.resident == 0 {
+		m.guard.endWrite()
+		return
+	}
+	blank := newEmptyMetadata()
+	for w, d := range m.dirty {
+		for ; d != 0; d &= d - 1 {
+			g := w<<6 + bits.TrailingZeros64(d)
+			// whole group stores compile to memclr
+			m.ctrl[g] = blank
+			m.groups[g] = group[K, V]{}
 		}
+		m.dirty[w] = 0
 	}
 	m.resident, m.dead = 0, 0
+	m.guard.endWrite()
+}
+
+// Reset removes all elements from the Map, like Clear, and shrinks its
+// table to the size needed to hold |shrinkTo| elements if it is larger.
+func (m *Map[K, V]) Reset(shrinkTo int) {
+	if shrinkTo < 0 {
+		shrinkTo = 0
+	}
+	n := numGroups(uint64(shrinkTo), m.maxLoad)
+	if n >= uint64(m.ngroups) {
+		m.Clear()
+		return
+	}
+	if m.hooks != nil {
+		defer m.hooks.cleared(int(n), time.Now())
+	}
+	m.guard.beginWrite()
+	m.release()
+	m.alloc(n)
+	m.reseeded = false
+	m.guard.endWrite()
+}
+
+// MemoryUsage returns the size in bytes of the Map's table.
+func (m *Map[K, V]) MemoryUsage() uint64 {
+	return tableSize[K, V](uint64(m.ngroups))
+}
+
+// SetMemoryLimit sets the maximum size in bytes that TryPut will grow
+// the Map's table to. A limit of zero removes the limit. The limit
+// does not apply to Put, nor to the old table while it is rehashed.
+func (m *Map[K, V]) SetMemoryLimit(bytes uint64) {
+	m.memLimit = bytes
 }
 
 // Count returns the number of elements in the Map.
@@ -266,69 +676,244 @@ func (m *Map[K, V]) Capacity() int {
 
 // find returns the location of |key| if present, or its insertion location if absent.
 // for performance, find is manually inlined into public methods.
-func (m *Map[K, V]) find(key K, hi h1, lo h2) (g, s uint32, ok bool) {
-	g = probeStart(hi, len(m.groups))
+func (m *Map[K, V]) find(key K, hi h1, lo h2) (g uint64, s uint32, ok bool) {
+	g = probeStart(hi, m.ngroups)
 	for {
-		matches := metaMatchH2(&m.ctrl[g], lo)
+		ctrl, grp := m.at(g)
+		matches := metaMatchH2(ctrl, lo)
 		for matches != 0 {
 			s = nextMatch(&matches)
-			if key == m.groups[g].keys[s] {
+			if key == grp.keys[s] {
 				return g, s, true
 			}
 		}
 		// |key| is not in group |g|,
 		// stop probing if we see an empty slot
-		matches = metaMatchEmpty(&m.ctrl[g])
+		matches = metaMatchEmpty(ctrl)
 		if matches != 0 {
 			s = nextMatch(&matches)
 			return g, s, false
 		}
 		g += 1 // linear probing
-		if g >= uint32(len(m.groups)) {
+		if g >= uint64(m.ngroups) {
 			g = 0
 		}
 	}
 }
 
-func (m *Map[K, V]) nextSize() (n uint32) {
-	n = uint32(len(m.groups)) * 2
-	if m.dead >= (m.resident / 2) {
-		n = uint32(len(m.groups))
+// nextSize returns the number of groups to rehash a full table into.
+func (m *Map[K, V]) nextSize() (n uint64) {
+	live := m.resident - m.dead
+	n = m.policy.NextSize(GrowthStats{
+		Groups:     uint64(m.ngroups),
+		Live:       live,
+		Tombstones: m.dead,
+		MaxLoad:    m.maxLoad,
+	})
+	// leave room for the element being inserted
+	if min := numGroups(live+1, m.maxLoad); n < min {
+		n = min
 	}
 	return
 }
 
-func (m *Map[K, V]) rehash(n uint32) {
-	groups, ctrl := m.groups, m.ctrl
-	m.groups = make([]group[K, V], n)
-	m.ctrl = make([]metadata, n)
+// reseed rebuilds the table with a new hash seed, breaking up long probe
+// sequences caused by colliding hashes. To bound the cost of keys that
+// collide under any seed, a table is reseeded at most once per size.
+func (m *Map[K, V]) reseed() {
+	if m.fixedSeed || m.reseeded || atomic.LoadUint32(&m.pinned) != 0 {
+		return
+	}
+	if m.hooks != nil {
+		defer m.hooks.reseeded(m.ngroups, time.Now())
+	}
+	if m.hash.fn != nil {
+		m.hash.seed = randIntN(math.MaxInt32)<<32 | randIntN(math.MaxInt32)
+	} else {
+		m.hash.Hasher = maphash.NewSeed(m.hash.Hasher)
+	}
+	m.seedGen++
+	m.rebuild(uint64(m.ngroups))
+	m.reseeded = true
+}
+
+// checkProbe reseeds the table if a key with hash prefix |hi|
+// was inserted abnormally far from its probe start, in group |g|.
+func (m *Map[K, V]) checkProbe(hi h1, g uint64) {
+	start := probeStart(hi, m.ngroups)
+	if g < start { // wrapped
+		g += uint64(m.ngroups)
+	}
+	if g-start > reseedThreshold(m.ngroups) {
+		m.reseed()
+	}
+}
+
+// reseedThreshold is the probe length in groups beyond which an insert
+// triggers a reseed. Probe lengths for random hashes grow with the
+// log of the table size, staying well under the threshold even at
+// the maximum load factor.
+func reseedThreshold(groups int) uint64 {
+	return 8 * uint64(bits.Len64(uint64(groups)))
+}
+
+// rehash moves all elements into a new table of |n| groups.
+// The hash seed is kept, so an element's position in hash
+// space, and therefore any Scan cursor, is stable.
+func (m *Map[K, V]) rehash(n uint64) {
+	if m.hooks != nil {
+		defer m.hooks.rehashed(m.ngroups, int(n), int(m.dead), time.Now())
+	}
+	if n != uint64(m.ngroups) {
+		m.reseeded = false
+	}
+	m.rebuild(n)
+}
+
+// rebuild moves all elements into a new table of |n| groups.
+func (m *Map[K, V]) rebuild(n uint64) {
+	// the old table is only read from, release
+	// it once its elements have been moved
+	old := m.table
+	m.alloc(n)
+	for g := uint64(0); g < uint64(old.ngroups); g++ {
+		ctrl, grp := old.at(g)
+		for s, b := range ctrl {
+			if b == empty || b == tombstone {
+				continue
+			}
+			m.insert(grp.keys[s], grp.values[s])
+		}
+	}
+	old.release()
+}
+
+// insert places |key| and |value| in the first free slot of their
+// probe sequence. |key| must be absent, and |m| must not be full.
+func (m *Map[K, V]) insert(key K, value V) {
+	hi, lo := splitHash(m.hash.hash(key))
+	g := probeStart(hi, m.ngroups)
+	for {
+		ctrl, grp := m.at(g)
+		matches := metaMatchEmpty(ctrl)
+		if matches != 0 {
+			s := nextMatch(&matches)
+			ctrl, grp = m.mut(g)
+			grp.keys[s] = key
+			grp.values[s] = value
+			ctrl[s] = int8(lo)
+			m.resident++
+			return
+		}
+		g += 1 // linear probing
+		if g >= uint64(m.ngroups) {
+			g = 0
+		}
+	}
+}
+
+// alloc replaces the table with an empty table of |n| groups.
+func (m *Map[K, V]) alloc(n uint64) {
+	m.table = table[K, V]{
+		ctrl:    make([]metadata, n),
+		groups:  make([]group[K, V], n),
+		ngroups: int(n),
+		dirty:   make([]uint64, (n+63)/64),
+	}
 	for i := range m.ctrl {
 		m.ctrl[i] = newEmptyMetadata()
 	}
-	m.hash = maphash.NewSeed(m.hash)
-	m.limit = n * maxAvgGroupLoad
+	m.limit = n * m.maxLoad
 	m.resident, m.dead = 0, 0
-	for g := range ctrl {
-		for s := range ctrl[g] {
-			c := ctrl[g][s]
-			if c == empty || c == tombstone {
-				continue
-			}
-			m.Put(groups[g].keys[s], groups[g].values[s])
+}
+
+// at returns the metadata and group |g| for reading.
+func (t *table[K, V]) at(g uint64) (*metadata, *group[K, V]) {
+	if t.chunks != nil {
+		return t.chunkAt(g)
+	}
+	return &t.ctrl[g], &t.groups[g]
+}
+
+func (t *table[K, V]) chunkAt(g uint64) (*metadata, *group[K, V]) {
+	c := t.chunks[g>>tableChunkShift]
+	return &c.ctrl[g&tableChunkMask], &c.groups[g&tableChunkMask]
+}
+
+// mut returns the metadata and group |g| for writing,
+// copying its chunk first if it is shared with a fork.
+func (t *table[K, V]) mut(g uint64) (*metadata, *group[K, V]) {
+	if t.chunks != nil {
+		return t.chunkMut(g)
+	}
+	t.dirty[g>>6] |= 1 << (g & 63)
+	return &t.ctrl[g], &t.groups[g]
+}
+
+func (t *table[K, V]) chunkMut(g uint64) (*metadata, *group[K, V]) {
+	i := g >> tableChunkShift
+	c := t.chunks[i]
+	if atomic.LoadInt32(&c.refs) > 1 {
+		// copy before releasing so that the chunk cannot
+		// be written by the last fork while it is copied
+		t.chunks[i] = &tableChunk[K, V]{
+			ctrl:   append([]metadata(nil), c.ctrl...),
+			groups: append([]group[K, V](nil), c.groups...),
+			refs:   1,
 		}
+		atomic.AddInt32(&c.refs, -1)
+		c = t.chunks[i]
+	}
+	return &c.ctrl[g&tableChunkMask], &c.groups[g&tableChunkMask]
+}
+
+// split divides the table into chunks, without copying
+// its groups, so that they can be shared with forks.
+func (t *table[K, V]) split() {
+	n := uint64(t.ngroups)
+	chunks := (n + tableChunkMask) >> tableChunkShift
+	t.chunks = make([]*tableChunk[K, V], chunks)
+	for i := range t.chunks {
+		lo := uint64(i) << tableChunkShift
+		hi := lo + tableChunkGroups
+		if hi > n {
+			hi = n
+		}
+		c := &tableChunk[K, V]{
+			ctrl:   t.ctrl[lo:hi:hi],
+			groups: t.groups[lo:hi:hi],
+			refs:   1,
+		}
+		t.chunks[i] = c
+	}
+	t.ctrl, t.groups, t.dirty = nil, nil, nil
+}
+
+// release drops the table's references to its chunks, if it was split.
+func (t *table[K, V]) release() {
+	for _, c := range t.chunks {
+		atomic.AddInt32(&c.refs, -1)
 	}
 }
 
 func (m *Map[K, V]) loadFactor() float32 {
-	slots := float32(len(m.groups) * groupSize)
+	slots := float32(m.ngroups * groupSize)
 	return float32(m.resident-m.dead) / slots
 }
 
-// numGroups returns the minimum number of groups needed to store |n| elems.
-func numGroups(n uint32) (groups uint32) {
-	groups = (n + maxAvgGroupLoad - 1) / maxAvgGroupLoad
-	if groups == 0 {
-		groups = 1
+// tableSize returns the size in bytes of a table of |n| groups.
+func tableSize[K comparable, V any](n uint64) uint64 {
+	per := unsafe.Sizeof(metadata{}) + unsafe.Sizeof(group[K, V]{})
+	return n * uint64(per)
+}
+
+// numGroups returns the minimum number of groups needed
+// to store |n| elems at an average of |load| per group.
+func numGroups(n, load uint64) (groups uint64) {
+	// avoid (n + load - 1), which overflows near the max
+	groups = n / load
+	if n%load != 0 || n == 0 {
+		groups++
 	}
 	return
 }
@@ -344,11 +929,15 @@ func splitHash(h uint64) (h1, h2) {
 	return h1((h & h1Mask) >> 7), h2(h & h2Mask)
 }
 
-func probeStart(hi h1, groups int) uint32 {
-	return fastModN(uint32(hi), uint32(groups))
+// probeStart maps |hi| to a group, reducing the 57 bit prefix
+// rather than its low bits so that tables of more than 2^32
+// groups are addressed uniformly.
+func probeStart(hi h1, groups int) uint64 {
+	return fastModN(uint64(hi)<<7, uint64(groups))
 }
 
 // lemire.me/blog/2016/06/27/a-fast-alternative-to-the-modulo-reduction/
-func fastModN(x, n uint32) uint32 {
-	return uint32((uint64(x) * uint64(n)) >> 32)
+func fastModN(x, n uint64) uint64 {
+	hi, _ := bits.Mul64(x, n)
+	return hi
 }
@@ -54,6 +54,43 @@ func BenchmarkInt64Maps(b *testing.B) {
 	}
 }
 
+func BenchmarkMapClear(b *testing.B) {
+	for _, n := range []int{0, 16, 1024} {
+		b.Run("n="+strconv.Itoa(n), func(b *testing.B) {
+			m := NewMap[int64, int64](1 << 20)
+			keys := generateInt64Data(n)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				for _, k := range keys {
+					m.Put(k, k)
+				}
+				m.Clear()
+			}
+		})
+	}
+}
+
+func BenchmarkLargeValues(b *testing.B) {
+	type value [256]byte
+	keys := generateInt64Data(100_000)
+	b.Run("swiss.Map", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			m := NewMap[int64, value](0)
+			for _, k := range keys {
+				m.Put(k, value{})
+			}
+		}
+	})
+	b.Run("swiss.IndirectMap", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			m := NewIndirectMap[int64, value](0)
+			for _, k := range keys {
+				m.Put(k, value{})
+			}
+		}
+	})
+}
+
 func TestMemoryFootprint(t *testing.T) {
 	t.Skip("unskip for memory footprint stats")
 	var samples []float64
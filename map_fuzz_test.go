@@ -59,6 +59,7 @@ func fuzzTestStringMap(t *testing.T, keySz, init, count uint32) {
 		golden[k] = i
 	}
 	assert.Equal(t, len(golden), m.Count())
+	assert.NoError(t, m.Validate())
 
 	for k, exp := range golden {
 		act, ok := m.Get(k)
@@ -77,6 +78,7 @@ func fuzzTestStringMap(t *testing.T, keySz, init, count uint32) {
 		m.Delete(k)
 	}
 	assert.Equal(t, len(golden), m.Count())
+	assert.NoError(t, m.Validate())
 
 	for _, k := range deletes {
 		assert.False(t, m.Has(k))
@@ -94,6 +96,10 @@ type hasher struct {
 }
 
 func setConstSeed[K comparable, V any](m *Map[K, V], seed uintptr) {
-	h := (*hasher)((unsafe.Pointer)(&m.hash))
+	h := (*hasher)((unsafe.Pointer)(&m.hash.Hasher))
 	h.seed = seed
 }
+
+func setHashFunc[K comparable, V any](m *Map[K, V], fn func(K, uint64) uint64, seed uint64) {
+	m.hash.fn, m.hash.seed = fn, seed
+}
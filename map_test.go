@@ -19,6 +19,7 @@ import (
 	"math"
 	"math/rand"
 	"testing"
+	"unsafe"
 
 	"github.com/stretchr/testify/require"
 
@@ -81,9 +82,21 @@ func testSwissMap[K comparable](t *testing.T, keys []K) {
 	t.Run("delete", func(t *testing.T) {
 		testMapDelete(t, keys)
 	})
+	t.Run("delete func", func(t *testing.T) {
+		testMapDeleteFunc(t, keys)
+	})
 	t.Run("clear", func(t *testing.T) {
 		testMapClear(t, keys)
 	})
+	t.Run("merge", func(t *testing.T) {
+		testMapMerge(t, keys)
+	})
+	t.Run("intersect", func(t *testing.T) {
+		testMapIntersectWith(t, keys)
+	})
+	t.Run("subtract", func(t *testing.T) {
+		testMapSubtract(t, keys)
+	})
 	t.Run("iter", func(t *testing.T) {
 		testMapIter(t, keys)
 	})
@@ -195,6 +208,41 @@ func testMapDelete[K comparable](t *testing.T, keys []K) {
 	assert.Equal(t, len(keys), m.Count())
 }
 
+func testMapDeleteFunc[K comparable](t *testing.T, keys []K) {
+	m := NewMap[K, int](uint32(len(keys)))
+	for i, key := range keys {
+		m.Put(key, i)
+	}
+	n := m.DeleteFunc(func(k K, v int) bool {
+		return v%2 == 0
+	})
+	assert.Equal(t, (len(keys)+1)/2, n)
+	assert.Equal(t, len(keys)-n, m.Count())
+	for i, key := range keys {
+		act, ok := m.Get(key)
+		if i%2 == 0 {
+			assert.False(t, ok)
+		} else {
+			assert.True(t, ok)
+			assert.Equal(t, i, act)
+		}
+	}
+	// delete everything that remains
+	n = m.DeleteFunc(func(k K, v int) bool {
+		return true
+	})
+	assert.Equal(t, len(keys)/2, n)
+	assert.Equal(t, 0, m.Count())
+	for _, key := range keys {
+		assert.False(t, m.Has(key))
+	}
+	// put keys back after deleting them
+	for i, key := range keys {
+		m.Put(key, i)
+	}
+	assert.Equal(t, len(keys), m.Count())
+}
+
 func testMapClear[K comparable](t *testing.T, keys []K) {
 	m := NewMap[K, int](0)
 	assert.Equal(t, 0, m.Count())
@@ -219,11 +267,192 @@ func testMapClear[K comparable](t *testing.T, keys []K) {
 
 	// Assert that the map was actually cleared...
 	var k K
-	for _, g := range m.groups {
-		for i := range g.keys {
-			assert.Equal(t, k, g.keys[i])
-			assert.Equal(t, 0, g.values[i])
+	for g := uint64(0); g < uint64(m.ngroups); g++ {
+		_, grp := m.at(g)
+		for i := range grp.keys {
+			assert.Equal(t, k, grp.keys[i])
+			assert.Equal(t, 0, grp.values[i])
+		}
+	}
+	assert.NoError(t, m.Validate())
+
+	// sparse tables with tombstones
+	for i, key := range keys[:len(keys)/10] {
+		m.Put(key, i)
+	}
+	for _, key := range keys[:len(keys)/20] {
+		m.Delete(key)
+	}
+	m.Clear()
+	assert.Equal(t, 0, m.Count())
+	assert.NoError(t, m.Validate())
+	m.Clear()
+	assert.NoError(t, m.Validate())
+}
+
+func TestMapClearDirty(t *testing.T) {
+	m := NewMap[uint32, int](100_000)
+	keys := genUint32Data(10)
+	for i, k := range keys {
+		m.Put(k, i)
+	}
+	dirty := make(map[uint64]bool)
+	for g := uint64(0); g < uint64(m.ngroups); g++ {
+		if m.dirty[g>>6]&(1<<(g&63)) != 0 {
+			dirty[g] = true
+		}
+	}
+	assert.LessOrEqual(t, len(dirty), len(keys))
+	// a group that was not written is not visited by Clear
+	var canary uint64
+	for dirty[canary] {
+		canary++
+	}
+	m.ctrl[canary][0] = 1
+	m.Clear()
+	assert.Equal(t, int8(1), m.ctrl[canary][0])
+	m.ctrl[canary][0] = empty
+	for _, d := range m.dirty {
+		assert.Zero(t, d)
+	}
+	assert.NoError(t, m.Validate())
+	for i, k := range keys {
+		assert.False(t, m.Has(k))
+		m.Put(k, i)
+	}
+	assert.Equal(t, len(keys), m.Count())
+	assert.NoError(t, m.Validate())
+}
+
+func TestMapReset(t *testing.T) {
+	keys := genUint32Data(10_000)
+	m := NewMap[uint32, int](0)
+	for i, k := range keys {
+		m.Put(k, i)
+	}
+	groups := m.ngroups
+	// Reset does not grow the table
+	m.Reset(len(keys) * 2)
+	assert.Equal(t, groups, m.ngroups)
+	assert.Equal(t, 0, m.Count())
+	assert.NoError(t, m.Validate())
+
+	for i, k := range keys {
+		m.Put(k, i)
+	}
+	f := m.Fork()
+	m.Reset(100)
+	assert.Equal(t, int(numGroups(100, maxAvgGroupLoad)), m.ngroups)
+	assert.Equal(t, 0, m.Count())
+	assert.GreaterOrEqual(t, m.Capacity(), 100)
+	assert.NoError(t, m.Validate())
+	for i, k := range keys {
+		assert.False(t, m.Has(k))
+		v, ok := f.Get(k)
+		assert.True(t, ok)
+		assert.Equal(t, i, v)
+	}
+	for i, k := range keys {
+		m.Put(k, i)
+	}
+	assert.Equal(t, len(keys), m.Count())
+	m.Reset(-1)
+	assert.Equal(t, 1, m.ngroups)
+	assert.NoError(t, m.Validate())
+}
+
+func testMapMerge[K comparable](t *testing.T, keys []K) {
+	// |a| holds the first two thirds of |keys|,
+	// |b| holds the last two thirds of |keys|
+	lo, hi := len(keys)/3, 2*len(keys)/3
+	a := NewMap[K, int](0)
+	for i, key := range keys[:hi] {
+		a.Put(key, i)
+	}
+	b := NewMap[K, int](uint32(len(keys)))
+	for i, key := range keys[lo:] {
+		b.Put(key, lo+i)
+	}
+	a.Merge(b, func(k K, x, y int) int {
+		assert.Equal(t, x, y)
+		return -x
+	})
+	assert.Equal(t, len(keys), a.Count())
+	for i, key := range keys {
+		act, ok := a.Get(key)
+		assert.True(t, ok)
+		if i >= lo && i < hi {
+			assert.Equal(t, -i, act)
+		} else {
+			assert.Equal(t, i, act)
+		}
+	}
+	assert.Equal(t, len(keys)-lo, b.Count())
+	// merge with self
+	a.Merge(a, func(k K, x, y int) int {
+		return x + y
+	})
+	assert.Equal(t, len(keys), a.Count())
+	for i, key := range keys {
+		act, _ := a.Get(key)
+		if i >= lo && i < hi {
+			assert.Equal(t, -2*i, act)
+		} else {
+			assert.Equal(t, 2*i, act)
+		}
+	}
+}
+
+func testMapIntersectWith[K comparable](t *testing.T, keys []K) {
+	lo, hi := len(keys)/3, 2*len(keys)/3
+	a := NewMap[K, int](uint32(len(keys)))
+	for i, key := range keys[:hi] {
+		a.Put(key, i)
+	}
+	b := NewMap[K, int](uint32(len(keys)))
+	for _, key := range keys[lo:] {
+		b.Put(key, -1)
+	}
+	a.IntersectWith(b)
+	assert.Equal(t, hi-lo, a.Count())
+	for i, key := range keys {
+		act, ok := a.Get(key)
+		if i >= lo && i < hi {
+			assert.True(t, ok)
+			assert.Equal(t, i, act)
+		} else {
+			assert.False(t, ok)
+		}
+	}
+	a.IntersectWith(a)
+	assert.Equal(t, hi-lo, a.Count())
+}
+
+func testMapSubtract[K comparable](t *testing.T, keys []K) {
+	lo, hi := len(keys)/3, 2*len(keys)/3
+	for _, split := range []int{lo, hi} {
+		// subtract both a smaller and a larger Map
+		a := NewMap[K, int](uint32(len(keys)))
+		for i, key := range keys {
+			a.Put(key, i)
 		}
+		b := NewMap[K, int](uint32(len(keys)))
+		for _, key := range keys[split:] {
+			b.Put(key, -1)
+		}
+		a.Subtract(b)
+		assert.Equal(t, split, a.Count())
+		for i, key := range keys {
+			act, ok := a.Get(key)
+			if i < split {
+				assert.True(t, ok)
+				assert.Equal(t, i, act)
+			} else {
+				assert.False(t, ok)
+			}
+		}
+		a.Subtract(a)
+		assert.Equal(t, 0, a.Count())
 	}
 }
 
@@ -362,20 +591,20 @@ func fmtProbeStats(s probeStats) string {
 }
 
 func getProbeLength[K comparable, V any](t *testing.T, m *Map[K, V], key K) (length uint32, ok bool) {
-	var end uint32
-	hi, lo := splitHash(m.hash.Hash(key))
-	start := probeStart(hi, len(m.groups))
+	var end uint64
+	hi, lo := splitHash(m.hash.hash(key))
+	start := probeStart(hi, m.ngroups)
 	end, _, ok = m.find(key, hi, lo)
 	if end < start { // wrapped
-		end += uint32(len(m.groups))
+		end += uint64(m.ngroups)
 	}
-	length = (end - start) + 1
+	length = uint32(end-start) + 1
 	require.True(t, length > 0)
 	return
 }
 
 func getProbeStats[K comparable, V any](t *testing.T, m *Map[K, V], keys []K) (stats probeStats) {
-	stats.groups = uint32(len(m.groups))
+	stats.groups = uint32(m.ngroups)
 	stats.loadFactor = m.loadFactor()
 	var presentSum, absentSum float32
 	stats.presentMin = math.MaxInt32
@@ -416,21 +645,343 @@ func getProbeStats[K comparable, V any](t *testing.T, m *Map[K, V], keys []K) (s
 }
 
 func TestNumGroups(t *testing.T) {
-	assert.Equal(t, expected(0), numGroups(0))
-	assert.Equal(t, expected(1), numGroups(1))
+	assert.Equal(t, expected(0), numGroups(0, maxAvgGroupLoad))
+	assert.Equal(t, expected(1), numGroups(1, maxAvgGroupLoad))
 	// max load factor 0.875
-	assert.Equal(t, expected(14), numGroups(14))
-	assert.Equal(t, expected(15), numGroups(15))
-	assert.Equal(t, expected(28), numGroups(28))
-	assert.Equal(t, expected(29), numGroups(29))
-	assert.Equal(t, expected(56), numGroups(56))
-	assert.Equal(t, expected(57), numGroups(57))
+	assert.Equal(t, expected(14), numGroups(14, maxAvgGroupLoad))
+	assert.Equal(t, expected(15), numGroups(15, maxAvgGroupLoad))
+	assert.Equal(t, expected(28), numGroups(28, maxAvgGroupLoad))
+	assert.Equal(t, expected(29), numGroups(29, maxAvgGroupLoad))
+	assert.Equal(t, expected(56), numGroups(56, maxAvgGroupLoad))
+	assert.Equal(t, expected(57), numGroups(57, maxAvgGroupLoad))
+	// boundaries of 32 and 64 bit sizes
+	const load = maxAvgGroupLoad
+	assert.Equal(t, uint64(math.MaxUint32/load+1), numGroups(math.MaxUint32, load))
+	assert.Equal(t, uint64(1<<32/load+1), numGroups(1<<32, load))
+	assert.Equal(t, uint64(1<<32), numGroups(1<<32*load, load))
+	assert.Equal(t, uint64(1<<32+1), numGroups(1<<32*load+1, load))
+	assert.Equal(t, uint64(math.MaxUint64/load+1), numGroups(math.MaxUint64, load))
+	assert.Equal(t, uint64(math.MaxUint64/load), numGroups(math.MaxUint64/load*load, load))
 }
 
-func expected(x int) (groups uint32) {
-	groups = uint32(math.Ceil(float64(x) / float64(maxAvgGroupLoad)))
+func expected(x int) (groups uint64) {
+	groups = uint64(math.Ceil(float64(x) / float64(maxAvgGroupLoad)))
 	if groups == 0 {
 		groups = 1
 	}
 	return
 }
+
+func TestMapFork(t *testing.T) {
+	keys := genStringData(16, 10_000)
+	t.Run("isolation", func(t *testing.T) {
+		m := NewMap[string, int](0)
+		for i, k := range keys {
+			m.Put(k, i)
+		}
+		f := m.Fork()
+		// mutate both sides
+		for i, k := range keys[:100] {
+			m.Put(k, -i)
+			f.Delete(k)
+		}
+		assert.Equal(t, len(keys), m.Count())
+		assert.Equal(t, len(keys)-100, f.Count())
+		for i, k := range keys {
+			act, ok := m.Get(k)
+			assert.True(t, ok)
+			fact, fok := f.Get(k)
+			if i < 100 {
+				assert.Equal(t, -i, act)
+				assert.False(t, fok)
+			} else {
+				assert.Equal(t, i, act)
+				assert.True(t, fok)
+				assert.Equal(t, i, fact)
+			}
+		}
+		assert.NoError(t, m.Validate())
+		assert.NoError(t, f.Validate())
+	})
+	t.Run("copy on write", func(t *testing.T) {
+		m := NewMap[string, int](0)
+		for i, k := range keys {
+			m.Put(k, i)
+		}
+		// unforked tables are not split
+		require.Nil(t, m.chunks)
+		f := m.Fork()
+		require.Greater(t, len(m.chunks), 1)
+		for i := range m.chunks {
+			assert.Same(t, m.chunks[i], f.chunks[i])
+		}
+		// only the chunk written to is copied
+		chunkOf := func(m *Map[string, int], k string) uint64 {
+			hi, lo := splitHash(m.hash.hash(k))
+			g, _, ok := m.find(k, hi, lo)
+			require.True(t, ok)
+			return g >> tableChunkShift
+		}
+		c := chunkOf(m, keys[0])
+		m.Put(keys[0], -1)
+		for i := range m.chunks {
+			if uint64(i) == c {
+				assert.NotSame(t, m.chunks[i], f.chunks[i])
+			} else {
+				assert.Same(t, m.chunks[i], f.chunks[i])
+			}
+		}
+		// |f| is now the only reference to its chunk
+		old := f.chunks[c]
+		f.Put(keys[0], -2)
+		assert.Same(t, old, f.chunks[c])
+		assert.NoError(t, m.Validate())
+		assert.NoError(t, f.Validate())
+		// clearing or rehashing a forked Map gives it a flat table
+		m.Clear()
+		assert.Nil(t, m.chunks)
+		assert.NoError(t, m.Validate())
+		f.rehash(f.nextSize())
+		assert.Nil(t, f.chunks)
+		assert.NoError(t, f.Validate())
+		for i, k := range keys[1:] {
+			v, ok := f.Get(k)
+			assert.True(t, ok)
+			assert.Equal(t, i+1, v)
+		}
+	})
+	t.Run("clear and grow", func(t *testing.T) {
+		m := NewMap[string, int](0)
+		for i, k := range keys[:1000] {
+			m.Put(k, i)
+		}
+		f1, f2 := m.Fork(), m.Fork()
+		m.Clear()
+		for i, k := range keys {
+			f1.Put(k, i)
+		}
+		assert.Equal(t, 0, m.Count())
+		assert.Equal(t, len(keys), f1.Count())
+		assert.Equal(t, 1000, f2.Count())
+		for i, k := range keys[:1000] {
+			act, ok := f2.Get(k)
+			assert.True(t, ok)
+			assert.Equal(t, i, act)
+		}
+	})
+	t.Run("concurrent readers", func(t *testing.T) {
+		m := NewMap[string, int](0)
+		for i, k := range keys {
+			m.Put(k, i)
+		}
+		done := make(chan struct{})
+		for r := 0; r < 4; r++ {
+			snap := m.Fork()
+			go func() {
+				defer func() { done <- struct{}{} }()
+				for i, k := range keys {
+					act, ok := snap.Get(k)
+					assert.True(t, ok)
+					assert.Equal(t, i, act)
+				}
+			}()
+			for i, k := range keys {
+				m.Put(k, i+r+1)
+				m.Put(k, i)
+			}
+		}
+		for r := 0; r < 4; r++ {
+			<-done
+		}
+	})
+}
+
+func TestMapHashed(t *testing.T) {
+	keys := genStringData(16, 10_000)
+	a := NewMap[string, int](0)
+	b := NewMapWithHasher[string, int](0, a.Hasher())
+	h := a.Hasher()
+	for i, k := range keys {
+		hash := h.Hash(k)
+		a.PutHashed(hash, k, i)
+		b.PutHashed(hash, k, -i)
+	}
+	// growth keeps the shared seed
+	seed := (*hasher)((unsafe.Pointer)(&h)).seed
+	for _, m := range []*Map[string, int]{a, b} {
+		mh := m.Hasher()
+		assert.Equal(t, seed, (*hasher)((unsafe.Pointer)(&mh)).seed)
+	}
+	for i, k := range keys {
+		hash := h.Hash(k)
+		assert.True(t, a.HasHashed(hash, k))
+		act, ok := a.GetHashed(hash, k)
+		assert.True(t, ok)
+		assert.Equal(t, i, act)
+		act, ok = b.Get(k)
+		assert.True(t, ok)
+		assert.Equal(t, -i, act)
+	}
+	for _, k := range keys[:5000] {
+		assert.True(t, a.DeleteHashed(h.Hash(k), k))
+		assert.False(t, a.DeleteHashed(h.Hash(k), k))
+	}
+	assert.Equal(t, 5000, a.Count())
+	for _, k := range keys[:5000] {
+		assert.False(t, a.Has(k))
+	}
+}
+
+func TestMapHashedPinsSeed(t *testing.T) {
+	keys := genUint32Data(1000)
+	k0 := keys[0]
+	for _, pin := range []func(m *Map[uint32, int]){
+		func(m *Map[uint32, int]) { m.Hasher() },
+		func(m *Map[uint32, int]) { m.Hash(k0) },
+		func(m *Map[uint32, int]) { m.HasHashed(m.hash.hash(k0), k0) },
+		func(m *Map[uint32, int]) { m.PutHashed(m.hash.hash(k0), k0, 0) },
+	} {
+		m := NewMap[uint32, int](0)
+		pin(m)
+		put := func() {
+			for i, k := range keys {
+				m.PutHashed(m.Hash(k), k, i)
+			}
+		}
+		put()
+		m.reseed()
+		assert.Equal(t, uint32(0), m.seedGen)
+		put()
+		assert.Equal(t, len(keys), m.Count())
+		for i, k := range keys {
+			v, ok := m.GetHashed(m.Hash(k), k)
+			assert.True(t, ok)
+			assert.Equal(t, i, v)
+		}
+		assert.NoError(t, m.Validate())
+	}
+	// other Maps still reseed
+	m := NewMap[uint32, int](0)
+	m.reseed()
+	assert.Equal(t, uint32(1), m.seedGen)
+}
+
+func TestMapMemoryLimit(t *testing.T) {
+	keys := genUint32Data(10_000)
+	m := NewMap[uint32, int](0)
+	per := uint64(unsafe.Sizeof(metadata{}) + unsafe.Sizeof(group[uint32, int]{}))
+	assert.Equal(t, per, m.MemoryUsage())
+
+	limit := 64 * per
+	m.SetMemoryLimit(limit)
+	var n int
+	for i, k := range keys {
+		if err := m.TryPut(k, i); err != nil {
+			assert.Equal(t, ErrMemoryLimit, err)
+			break
+		}
+		n++
+		assert.LessOrEqual(t, m.MemoryUsage(), limit)
+	}
+	assert.Equal(t, n, m.Count())
+	assert.Equal(t, 0, m.Capacity())
+	assert.Less(t, n, len(keys))
+	// updates do not need to grow the table
+	for i, k := range keys[:n] {
+		assert.NoError(t, m.TryPut(k, -i))
+	}
+	for i, k := range keys[:n] {
+		act, ok := m.Get(k)
+		assert.True(t, ok)
+		assert.Equal(t, -i, act)
+	}
+	// Put ignores the limit
+	m.Put(keys[n], n)
+	assert.Greater(t, m.MemoryUsage(), limit)
+	m.SetMemoryLimit(0)
+	for i, k := range keys {
+		assert.NoError(t, m.TryPut(k, i))
+	}
+	assert.Equal(t, len(keys), m.Count())
+}
+
+func TestMapReseed(t *testing.T) {
+	// under |badSeed| every key hashes to the same
+	// probe start, under other seeds keys are mixed
+	const badSeed = 42
+	collide := func(k uint64, seed uint64) uint64 {
+		if seed == badSeed {
+			return k & 0xfff
+		}
+		return mix64(k ^ seed)
+	}
+	build := func(m *Map[uint64, int], n int) (keys []uint64) {
+		setHashFunc(m, collide, badSeed)
+		for i := 0; i < n; i++ {
+			keys = append(keys, uint64(i))
+			m.Put(uint64(i), i)
+		}
+		return
+	}
+	maxProbe := func(m *Map[uint64, int], keys []uint64) (max uint64) {
+		for _, k := range keys {
+			l, ok := getProbeLength(t, m, k)
+			require.True(t, ok)
+			if uint64(l) > max {
+				max = uint64(l)
+			}
+		}
+		return
+	}
+	const n = 4096
+	m := NewMap[uint64, int](n)
+	keys := build(m, n)
+	assert.Equal(t, uint32(1), m.seedGen)
+	assert.NotEqual(t, uint64(badSeed), m.hash.seed)
+	assert.LessOrEqual(t, maxProbe(m, keys), reseedThreshold(m.ngroups))
+	for i, k := range keys {
+		act, ok := m.Get(k)
+		assert.True(t, ok)
+		assert.Equal(t, i, act)
+	}
+	assert.NoError(t, m.Validate())
+
+	// a Map with a shared Hasher keeps its seed
+	f := NewMapWithHasher[uint64, int](n, m.Hasher())
+	keys = build(f, n)
+	assert.Equal(t, uint32(0), f.seedGen)
+	assert.Greater(t, maxProbe(f, keys), reseedThreshold(f.ngroups))
+	assert.NoError(t, f.Validate())
+
+	// keys inserted through upsert are checked too
+	c := NewCounter[uint64](n)
+	setHashFunc(c.m, collide, badSeed)
+	for _, k := range keys {
+		c.Add(k, int64(k))
+	}
+	assert.Equal(t, uint32(1), c.m.seedGen)
+	for _, k := range keys {
+		assert.Equal(t, int64(k), c.Get(k))
+	}
+	assert.NoError(t, c.m.Validate())
+
+	// and keys inserted by Merge
+	mm := NewMap[uint64, int](n)
+	setHashFunc(mm, collide, badSeed)
+	mm.Merge(m, func(k uint64, a, b int) int {
+		return b
+	})
+	assert.Equal(t, uint32(1), mm.seedGen)
+	assert.LessOrEqual(t, maxProbe(mm, keys), reseedThreshold(mm.ngroups))
+	assert.NoError(t, mm.Validate())
+}
+
+// mix64 is the splitmix64 finalizer.
+func mix64(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
@@ -0,0 +1,220 @@
+// Copyright 2023 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swiss
+
+// chunkSize is the number of values stored per chunk.
+const chunkSize = 8
+
+// MultiMap is a hash map that associates each key with one or more
+// values. Keys are indexed by a Map, each key's values are stored
+// in a linked list of fixed-size chunks allocated from a shared
+// slab, so adding a value never allocates per key.
+type MultiMap[K, V comparable] struct {
+	index  *Map[K, valueList]
+	chunks []valueChunk[V]
+	free   uint32
+	count  int
+}
+
+// valueList is the list of chunks holding a key's values.
+// Only the tail chunk may be partially full.
+type valueList struct {
+	head, tail uint32
+	count      uint32
+}
+
+type valueChunk[V comparable] struct {
+	values [chunkSize]V
+	n      uint32
+	next   uint32
+}
+
+// NewMultiMap constructs a MultiMap.
+func NewMultiMap[K, V comparable](sz uint32) *MultiMap[K, V] {
+	return &MultiMap[K, V]{
+		index: NewMap[K, valueList](sz),
+		free:  nilEntry,
+	}
+}
+
+// Add associates |value| with |key|.
+func (m *MultiMap[K, V]) Add(key K, value V) {
+	l, _ := m.index.upsert(key)
+	if l.count == 0 {
+		c := m.allocChunk()
+		l.head, l.tail = c, c
+	} else if m.chunks[l.tail].n == chunkSize {
+		c := m.allocChunk()
+		m.chunks[l.tail].next = c
+		l.tail = c
+	}
+	t := &m.chunks[l.tail]
+	t.values[t.n] = value
+	t.n++
+	l.count++
+	m.count++
+}
+
+// GetAll passes each value associated with |key| to the callback.
+// Values are not visited in any particular order.
+func (m *MultiMap[K, V]) GetAll(key K, cb func(v V) (stop bool)) {
+	l, ok := m.index.Get(key)
+	if !ok {
+		return
+	}
+	for c := l.head; c != nilEntry; c = m.chunks[c].next {
+		ch := &m.chunks[c]
+		for i := uint32(0); i < ch.n; i++ {
+			if stop := cb(ch.values[i]); stop {
+				return
+			}
+		}
+	}
+}
+
+// Has returns true if any value is associated with |key|.
+func (m *MultiMap[K, V]) Has(key K) bool {
+	return m.index.Has(key)
+}
+
+// CountKey returns the number of values associated with |key|.
+func (m *MultiMap[K, V]) CountKey(key K) int {
+	l, _ := m.index.Get(key)
+	return int(l.count)
+}
+
+// RemoveOne removes one association of |value| with |key|,
+// returns true successful.
+func (m *MultiMap[K, V]) RemoveOne(key K, value V) (ok bool) {
+	l, found := m.index.Get(key)
+	if !found {
+		return false
+	}
+	// find |value|, and the chunk preceding the tail
+	prev := nilEntry
+	var at *V
+	for c := l.head; c != l.tail; c = m.chunks[c].next {
+		prev = c
+		if at == nil {
+			at = m.chunks[c].find(value)
+		}
+	}
+	t := &m.chunks[l.tail]
+	if at == nil {
+		if at = t.find(value); at == nil {
+			return false
+		}
+	}
+	// fill the hole with the last value
+	t.n--
+	*at = t.values[t.n]
+	var zero V
+	t.values[t.n] = zero
+	l.count--
+	m.count--
+	if l.count == 0 {
+		m.freeChunk(l.tail)
+		m.index.Delete(key)
+		return true
+	}
+	if t.n == 0 {
+		m.freeChunk(l.tail)
+		m.chunks[prev].next = nilEntry
+		l.tail = prev
+	}
+	m.index.Put(key, l)
+	return true
+}
+
+// RemoveAll removes every value associated with |key|,
+// and returns the number of values removed.
+func (m *MultiMap[K, V]) RemoveAll(key K) int {
+	l, ok := m.index.Get(key)
+	if !ok {
+		return 0
+	}
+	for c := l.head; c != nilEntry; {
+		next := m.chunks[c].next
+		m.freeChunk(c)
+		c = next
+	}
+	m.index.Delete(key)
+	m.count -= int(l.count)
+	return int(l.count)
+}
+
+// Iter iterates each key-value association of the MultiMap,
+// passing them to the callback. The callback must not mutate |m|.
+func (m *MultiMap[K, V]) Iter(cb func(k K, v V) (stop bool)) {
+	m.index.Iter(func(k K, l valueList) (stop bool) {
+		for c := l.head; c != nilEntry; c = m.chunks[c].next {
+			ch := &m.chunks[c]
+			for i := uint32(0); i < ch.n; i++ {
+				if stop = cb(k, ch.values[i]); stop {
+					return
+				}
+			}
+		}
+		return
+	})
+}
+
+// Clear removes all elements from the MultiMap.
+func (m *MultiMap[K, V]) Clear() {
+	m.index.Clear()
+	var c valueChunk[V]
+	for i := range m.chunks {
+		m.chunks[i] = c
+	}
+	m.chunks = m.chunks[:0]
+	m.free = nilEntry
+	m.count = 0
+}
+
+// Count returns the number of key-value associations in the MultiMap.
+func (m *MultiMap[K, V]) Count() int {
+	return m.count
+}
+
+// KeyCount returns the number of distinct keys in the MultiMap.
+func (m *MultiMap[K, V]) KeyCount() int {
+	return m.index.Count()
+}
+
+func (m *MultiMap[K, V]) allocChunk() (c uint32) {
+	if m.free != nilEntry {
+		c = m.free
+		m.free = m.chunks[c].next
+		m.chunks[c].next = nilEntry
+		return
+	}
+	c = uint32(len(m.chunks))
+	m.chunks = append(m.chunks, valueChunk[V]{next: nilEntry})
+	return
+}
+
+func (m *MultiMap[K, V]) freeChunk(c uint32) {
+	m.chunks[c] = valueChunk[V]{next: m.free}
+	m.free = c
+}
+
+func (c *valueChunk[V]) find(value V) *V {
+	for i := uint32(0); i < c.n; i++ {
+		if c.values[i] == value {
+			return &c.values[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+// Copyright 2023 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swiss
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMultiMap(t *testing.T) {
+	t.Run("add get", func(t *testing.T) {
+		keys := genStringData(16, 1000)
+		m := NewMultiMap[string, int](0)
+		// keys[i] has |i%20| values
+		for i, k := range keys {
+			for j := 0; j < i%20; j++ {
+				m.Add(k, j)
+			}
+		}
+		var total int
+		for i, k := range keys {
+			assert.Equal(t, i%20, m.CountKey(k))
+			assert.Equal(t, i%20 > 0, m.Has(k))
+			assert.Equal(t, seq(i%20), getAll(m, k))
+			total += i % 20
+		}
+		assert.Equal(t, total, m.Count())
+		var n int
+		m.Iter(func(k string, v int) (stop bool) {
+			n++
+			return
+		})
+		assert.Equal(t, total, n)
+	})
+	t.Run("remove", func(t *testing.T) {
+		m := NewMultiMap[int, int](0)
+		for j := 0; j < 20; j++ {
+			m.Add(1, j)
+			m.Add(2, j)
+		}
+		assert.False(t, m.RemoveOne(1, 20))
+		assert.False(t, m.RemoveOne(3, 0))
+		// remove from the head chunk, the tail chunk
+		// empties and is returned to the free list
+		for j := 0; j < 4; j++ {
+			assert.True(t, m.RemoveOne(1, j))
+		}
+		assert.Equal(t, seq(20)[4:], getAll(m, 1))
+		assert.Equal(t, 20, m.RemoveAll(2))
+		assert.Equal(t, 0, m.RemoveAll(2))
+		assert.False(t, m.Has(2))
+		assert.Equal(t, 16, m.Count())
+		assert.Equal(t, 1, m.KeyCount())
+		// freed chunks are reused
+		n := len(m.chunks)
+		for j := 0; j < 20; j++ {
+			m.Add(3, j)
+		}
+		assert.Equal(t, n, len(m.chunks))
+		m.Clear()
+		assert.Equal(t, 0, m.Count())
+		assert.Equal(t, 0, m.KeyCount())
+		assert.False(t, m.Has(1))
+	})
+	t.Run("random ops", func(t *testing.T) {
+		const keys, values = 32, 8
+		m := NewMultiMap[int, int](0)
+		golden := make(map[int][]int)
+		for i := 0; i < 100_000; i++ {
+			k, v := rand.Intn(keys), rand.Intn(values)
+			switch rand.Intn(3) {
+			case 0, 1:
+				m.Add(k, v)
+				golden[k] = append(golden[k], v)
+			case 2:
+				idx := -1
+				for j, x := range golden[k] {
+					if x == v {
+						idx = j
+						break
+					}
+				}
+				require.Equal(t, idx >= 0, m.RemoveOne(k, v))
+				if idx >= 0 {
+					golden[k] = append(golden[k][:idx], golden[k][idx+1:]...)
+				}
+				if len(golden[k]) == 0 {
+					delete(golden, k)
+				}
+			}
+		}
+		var total int
+		for k, vals := range golden {
+			sort.Ints(vals)
+			require.Equal(t, vals, getAll(m, k))
+			total += len(vals)
+		}
+		require.Equal(t, total, m.Count())
+		require.Equal(t, len(golden), m.KeyCount())
+	})
+}
+
+func seq(n int) (s []int) {
+	s = make([]int, n)
+	for i := range s {
+		s[i] = i
+	}
+	return
+}
+
+// getAll returns the values of |key| in sorted order.
+func getAll[K comparable](m *MultiMap[K, int], key K) []int {
+	vals := make([]int, 0)
+	m.GetAll(key, func(v int) (stop bool) {
+		vals = append(vals, v)
+		return
+	})
+	sort.Ints(vals)
+	return vals
+}
+
+func BenchmarkMultiMapAdd(b *testing.B) {
+	keys := generateInt64Data(1024)
+	b.Run("swiss.MultiMap", func(b *testing.B) {
+		m := NewMultiMap[int64, int](uint32(len(keys)))
+		for i := 0; i < b.N; i++ {
+			m.Add(keys[i&1023], i)
+		}
+		b.ReportAllocs()
+	})
+	b.Run("swiss.Map[K, []V]", func(b *testing.B) {
+		m := NewMap[int64, []int](uint32(len(keys)))
+		for i := 0; i < b.N; i++ {
+			k := keys[i&1023]
+			vals, _ := m.Get(k)
+			m.Put(k, append(vals, i))
+		}
+		b.ReportAllocs()
+	})
+}
@@ -0,0 +1,254 @@
+// Copyright 2023 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swiss
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sync"
+
+	"github.com/dolthub/maphash"
+)
+
+// ErrInvalidOption is returned by New if an Option is out of range
+// or does not apply to the Map's key type.
+var ErrInvalidOption = errors.New("swiss: invalid option")
+
+// Option configures a Map constructed by New.
+type Option func(o *options) error
+
+type options struct {
+	capacity uint64
+	maxLoad  uint64
+	growth   Growth
+	// growthSet is set if |growth| was modified
+	growthSet bool
+	policy    GrowthPolicy
+	memLimit  uint64
+	hooks     *Hooks
+	// hasher is a maphash.Hasher[K]
+	hasher any
+	// hashFunc is a func(K, uint64) uint64
+	hashFunc any
+	seed     uint64
+	hasSeed  bool
+}
+
+// New constructs a Map configured by |opts|. It returns an error
+// wrapping ErrInvalidOption if any option is invalid.
+func New[K comparable, V any](opts ...Option) (*Map[K, V], error) {
+	o := options{maxLoad: maxAvgGroupLoad, growth: DefaultGrowth()}
+	for _, opt := range opts {
+		if err := opt(&o); err != nil {
+			return nil, err
+		}
+	}
+	if o.hasher != nil && (o.hashFunc != nil || o.hasSeed) {
+		return nil, fmt.Errorf("%w: WithHasher excludes WithHashFunc and WithSeed", ErrInvalidOption)
+	}
+	if o.policy != nil && o.growthSet {
+		return nil, fmt.Errorf("%w: WithGrowthPolicy excludes WithGrowthFactor and WithShrink", ErrInvalidOption)
+	}
+	if o.policy == nil && o.growthSet {
+		if err := o.growth.validate(); err != nil {
+			return nil, err
+		}
+		o.policy = o.growth
+	}
+
+	m := newMap[K, V](o.capacity, o.maxLoad)
+	if o.policy != nil {
+		m.policy = o.policy
+	}
+	m.memLimit = o.memLimit
+	m.hooks = o.hooks
+	if o.hasher != nil {
+		h, ok := o.hasher.(maphash.Hasher[K])
+		if !ok {
+			return nil, fmt.Errorf("%w: %T for key type %T", ErrInvalidOption, o.hasher, *new(K))
+		}
+		m.hash.Hasher, m.fixedSeed = h, true
+	}
+	if o.hashFunc != nil {
+		fn, ok := o.hashFunc.(func(K, uint64) uint64)
+		if !ok {
+			return nil, fmt.Errorf("%w: %T for key type %T", ErrInvalidOption, o.hashFunc, *new(K))
+		}
+		m.hash = keyHasher[K]{fn: fn, seed: randIntN(math.MaxInt32)<<32 | randIntN(math.MaxInt32)}
+	}
+	if o.hasSeed {
+		if m.hash.fn == nil {
+			m.hash = keyHasher[K]{fn: seededHash[K]()}
+		}
+		m.hash.seed = o.seed
+		m.fixedSeed = true
+	}
+	return m, nil
+}
+
+// WithCapacity sets the number of elements the Map
+// can hold before it first grows.
+func WithCapacity(n int) Option {
+	return func(o *options) error {
+		if n < 0 {
+			return fmt.Errorf("%w: capacity %d", ErrInvalidOption, n)
+		}
+		o.capacity = uint64(n)
+		return nil
+	}
+}
+
+// WithMaxLoad sets the fraction of slots that may be filled before
+// the Map grows. Higher loads save memory at the cost of longer
+// probe sequences. |load| must be in (0, 1), and is rounded down
+// to a whole number of slots per group.
+func WithMaxLoad(load float64) Option {
+	return func(o *options) error {
+		n := uint64(load * groupSize)
+		if !(load > 0 && load < 1) || n == 0 {
+			return fmt.Errorf("%w: max load %g", ErrInvalidOption, load)
+		}
+		o.maxLoad = n
+		return nil
+	}
+}
+
+// WithGrowthFactor sets the factor by which the table grows when
+// full, see Growth.Factor. The default is 2.
+func WithGrowthFactor(factor float64) Option {
+	return func(o *options) error {
+		o.growth.Factor, o.growthSet = factor, true
+		return nil
+	}
+}
+
+// WithShrink lets the table shrink when it is compacted, if fewer than
+// |minLoad| of its maximum load are live, see Growth.ShrinkBelow. By
+// default tables do not shrink.
+func WithShrink(minLoad float64) Option {
+	return func(o *options) error {
+		o.growth.ShrinkBelow, o.growthSet = minLoad, true
+		return nil
+	}
+}
+
+// WithGrowthPolicy sets the policy that sizes the table when it is
+// rebuilt. It excludes WithGrowthFactor and WithShrink.
+func WithGrowthPolicy(p GrowthPolicy) Option {
+	return func(o *options) error {
+		if p == nil {
+			return fmt.Errorf("%w: nil growth policy", ErrInvalidOption)
+		}
+		if g, ok := p.(Growth); ok {
+			if err := g.validate(); err != nil {
+				return err
+			}
+		}
+		o.policy = p
+		return nil
+	}
+}
+
+// WithHashFunc sets the function used to hash keys. |fn| must mix
+// |seed| into the hash, as the Map changes seeds to defend against
+// hash flooding. K must be the key type of the Map.
+func WithHashFunc[K comparable](fn func(key K, seed uint64) uint64) Option {
+	return func(o *options) error {
+		if fn == nil {
+			return fmt.Errorf("%w: nil hash func", ErrInvalidOption)
+		}
+		o.hashFunc = fn
+		return nil
+	}
+}
+
+// WithSeed sets the seed the Map's keys are hashed with. Seeded
+// Maps do not change seeds to defend against hash flooding.
+func WithSeed(seed uint64) Option {
+	return func(o *options) error {
+		o.seed, o.hasSeed = seed, true
+		return nil
+	}
+}
+
+// WithHasher sets the Hasher used to hash keys, like NewMapWithHasher.
+// K must be the key type of the Map.
+func WithHasher[K comparable](h maphash.Hasher[K]) Option {
+	return func(o *options) error {
+		if err := checkHasher(h); err != nil {
+			return err
+		}
+		o.hasher = h
+		return nil
+	}
+}
+
+// checkHasher returns an error if |h| is the zero Hasher, as returned
+// by the Hasher method of Maps that do not hash keys with a Hasher.
+func checkHasher[K comparable](h maphash.Hasher[K]) error {
+	if reflect.ValueOf(h).IsZero() {
+		return fmt.Errorf("%w: zero Hasher", ErrInvalidOption)
+	}
+	return nil
+}
+
+// WithHooks sets the Map's Hooks, like NewMapWithHooks.
+func WithHooks(hooks Hooks) Option {
+	return func(o *options) error {
+		o.hooks = &hooks
+		return nil
+	}
+}
+
+// WithMemoryLimit sets the Map's memory limit, see SetMemoryLimit.
+func WithMemoryLimit(bytes uint64) Option {
+	return func(o *options) error {
+		o.memLimit = bytes
+		return nil
+	}
+}
+
+// keyHasher hashes the keys of a Map. It hashes with |fn| and |seed|
+// if the Map was configured with WithHashFunc or WithSeed, and with
+// its Hasher otherwise.
+type keyHasher[K comparable] struct {
+	maphash.Hasher[K]
+	fn   func(key K, seed uint64) uint64
+	seed uint64
+}
+
+func (h *keyHasher[K]) hash(key K) uint64 {
+	if h.fn != nil {
+		return h.fn(key, h.seed)
+	}
+	return h.Hasher.Hash(key)
+}
+
+// runtimeHashers holds a maphash.Hasher per key type, shared by Maps
+// configured with WithSeed but not WithHashFunc.
+var runtimeHashers sync.Map
+
+// seededHash returns a hash function that mixes |seed| into the
+// runtime hash of K, so that Maps with equal seeds hash alike.
+func seededHash[K comparable]() func(key K, seed uint64) uint64 {
+	t := reflect.TypeOf((*K)(nil)).Elem()
+	h, _ := runtimeHashers.LoadOrStore(t, maphash.NewHasher[K]())
+	base := h.(maphash.Hasher[K])
+	return func(key K, seed uint64) uint64 {
+		return mulMix(base.Hash(key)^seed, 0x9e3779b97f4a7c15)
+	}
+}
@@ -0,0 +1,184 @@
+// Copyright 2023 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swiss
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dolthub/maphash"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		m, err := New[uint32, int]()
+		require.NoError(t, err)
+		exp := NewMap[uint32, int](0)
+		assert.Equal(t, exp.ngroups, m.ngroups)
+		assert.Equal(t, exp.limit, m.limit)
+		testOptions(t, m)
+	})
+	t.Run("capacity", func(t *testing.T) {
+		m, err := New[uint32, int](WithCapacity(1000))
+		require.NoError(t, err)
+		assert.GreaterOrEqual(t, m.Capacity(), 1000)
+		testOptions(t, m)
+	})
+	t.Run("max load", func(t *testing.T) {
+		m, err := New[uint32, int](WithCapacity(1000), WithMaxLoad(0.5))
+		require.NoError(t, err)
+		assert.Equal(t, uint64(groupSize/2), m.maxLoad)
+		assert.Equal(t, int(numGroups(1000, groupSize/2)), m.ngroups)
+		testOptions(t, m)
+		assert.LessOrEqual(t, m.loadFactor(), float32(0.5))
+	})
+	t.Run("growth factor", func(t *testing.T) {
+		m, err := New[uint32, int](WithCapacity(100*maxAvgGroupLoad), WithGrowthFactor(1.5))
+		require.NoError(t, err)
+		for i := 0; i <= 100*maxAvgGroupLoad; i++ {
+			m.Put(uint32(i), i)
+		}
+		assert.Equal(t, 150, m.ngroups)
+		testOptions(t, m)
+	})
+	t.Run("shrink", func(t *testing.T) {
+		m, err := New[uint32, int](WithShrink(0.25))
+		require.NoError(t, err)
+		keys := genUint32Data(10_000)
+		for i, k := range keys {
+			m.Put(k, i)
+		}
+		groups := m.ngroups
+		m.DeleteFunc(func(k uint32, v int) bool {
+			return v >= 100
+		})
+		assert.Less(t, m.ngroups, groups)
+		assert.Equal(t, 100, m.Count())
+		assert.NoError(t, m.Validate())
+		// without the option, the table keeps its size
+		m = NewMap[uint32, int](0)
+		for i, k := range keys {
+			m.Put(k, i)
+		}
+		m.DeleteFunc(func(k uint32, v int) bool {
+			return v >= 100
+		})
+		assert.Equal(t, groups, m.ngroups)
+	})
+	t.Run("hash func", func(t *testing.T) {
+		var calls int
+		m, err := New[uint64, int](WithHashFunc(func(k uint64, seed uint64) uint64 {
+			calls++
+			return mix64(k ^ seed)
+		}))
+		require.NoError(t, err)
+		testOptions(t, m)
+		assert.Greater(t, calls, 0)
+		assert.Equal(t, mix64(7^m.hash.seed), m.Hash(7))
+		assert.False(t, m.fixedSeed)
+	})
+	t.Run("seed", func(t *testing.T) {
+		a, err := New[uint64, int](WithSeed(42))
+		require.NoError(t, err)
+		b, err := New[uint64, int](WithSeed(42))
+		require.NoError(t, err)
+		c, err := New[uint64, int](WithSeed(43))
+		require.NoError(t, err)
+		assert.Equal(t, a.Hash(7), b.Hash(7))
+		assert.NotEqual(t, a.Hash(7), c.Hash(7))
+		assert.True(t, a.fixedSeed)
+		testOptions(t, a)
+		// the seed is passed to the hash func
+		d, err := New[uint64, int](WithSeed(42), WithHashFunc(func(k uint64, seed uint64) uint64 {
+			return mix64(k ^ seed)
+		}))
+		require.NoError(t, err)
+		assert.Equal(t, mix64(7^42), d.Hash(7))
+		testOptions(t, d)
+		// seeded Maps have no Hasher to share
+		_, err = New[uint64, int](WithHasher(a.Hasher()))
+		assert.ErrorIs(t, err, ErrInvalidOption)
+		assert.Panics(t, func() { NewMapWithHasher[uint64, int](0, a.Hasher()) })
+	})
+	t.Run("hasher", func(t *testing.T) {
+		h := maphash.NewHasher[string]()
+		m, err := New[string, int](WithHasher(h))
+		require.NoError(t, err)
+		assert.Equal(t, h.Hash("swiss"), m.Hasher().Hash("swiss"))
+		assert.True(t, m.fixedSeed)
+	})
+	t.Run("hooks and memory limit", func(t *testing.T) {
+		var grew int
+		m, err := New[uint32, int](
+			WithHooks(Hooks{OnGrow: func(_, _ int, _ time.Duration) { grew++ }}),
+			WithMemoryLimit(1))
+		require.NoError(t, err)
+		for i := 0; i < maxAvgGroupLoad; i++ {
+			assert.NoError(t, m.TryPut(uint32(i), i))
+		}
+		assert.ErrorIs(t, m.TryPut(maxAvgGroupLoad, 0), ErrMemoryLimit)
+		m.Put(maxAvgGroupLoad, 0)
+		assert.Equal(t, 1, grew)
+	})
+}
+
+func TestNewInvalid(t *testing.T) {
+	invalid := [][]Option{
+		{WithCapacity(-1)},
+		{WithMaxLoad(0)},
+		{WithMaxLoad(1)},
+		{WithMaxLoad(-0.5)},
+		{WithMaxLoad(0.5 / groupSize)},
+		{WithGrowthFactor(1)},
+		{WithGrowthFactor(0.5)},
+		{WithGrowthFactor(maxGrowth + 1)},
+		{WithShrink(-0.1)},
+		{WithShrink(1)},
+		{WithShrink(0.6)},
+		{WithGrowthFactor(4), WithShrink(0.3)},
+		{WithHashFunc[uint64](nil)},
+		{WithHashFunc(func(k string, seed uint64) uint64 { return 0 })},
+		{WithHasher(maphash.NewHasher[string]())},
+		{WithHasher(maphash.NewHasher[uint64]()), WithSeed(1)},
+		{WithHasher(maphash.Hasher[uint64]{})},
+	}
+	for _, opts := range invalid {
+		m, err := New[uint64, int](opts...)
+		assert.ErrorIs(t, err, ErrInvalidOption)
+		assert.Nil(t, m)
+	}
+}
+
+// testOptions checks that a configured Map behaves like a Map.
+func testOptions[V ~int, K interface{ ~uint32 | ~uint64 }](t *testing.T, m *Map[K, V]) {
+	const n = 10_000
+	for i := 0; i < n; i++ {
+		m.Put(K(i), V(i))
+	}
+	for i := 0; i < n; i += 2 {
+		assert.True(t, m.Delete(K(i)))
+	}
+	assert.Equal(t, n/2, m.Count())
+	for i := 0; i < n; i++ {
+		v, ok := m.Get(K(i))
+		assert.Equal(t, i%2 == 1, ok)
+		if ok {
+			assert.Equal(t, V(i), v)
+		}
+	}
+	assert.NoError(t, m.Validate())
+}
@@ -0,0 +1,140 @@
+// Copyright 2023 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swiss
+
+// OrderedMap is a hash map that iterates in insertion order.
+// Keys are indexed by a Map, entries are kept in a dense
+// slab linked in insertion order.
+type OrderedMap[K comparable, V any] struct {
+	index *Map[K, uint32]
+	list  entryList[K, V]
+}
+
+// NewOrderedMap constructs an OrderedMap.
+func NewOrderedMap[K comparable, V any](sz uint32) *OrderedMap[K, V] {
+	return &OrderedMap[K, V]{
+		index: NewMap[K, uint32](sz),
+		list:  newEntryList[K, V](sz),
+	}
+}
+
+// Has returns true if |key| is present in |m|.
+func (m *OrderedMap[K, V]) Has(key K) bool {
+	return m.index.Has(key)
+}
+
+// Get returns the |value| mapped by |key| if one exists.
+func (m *OrderedMap[K, V]) Get(key K) (value V, ok bool) {
+	var i uint32
+	if i, ok = m.index.Get(key); ok {
+		value = m.list.entries[i].value
+	}
+	return
+}
+
+// Put attempts to insert |key| and |value|. New keys are
+// placed at the back of the order, existing keys are
+// updated without changing their position.
+func (m *OrderedMap[K, V]) Put(key K, value V) {
+	i, ok := m.index.upsert(key)
+	if ok {
+		m.list.entries[*i].value = value
+		return
+	}
+	*i = m.list.pushBack(key, value)
+}
+
+// Delete attempts to remove |key|, returns true successful.
+func (m *OrderedMap[K, V]) Delete(key K) (ok bool) {
+	var i uint32
+	if i, ok = m.index.Get(key); ok {
+		m.index.Delete(key)
+		m.list.remove(i)
+	}
+	return
+}
+
+// MoveToBack moves |key| to the back of the order,
+// returns false if |key| is absent.
+func (m *OrderedMap[K, V]) MoveToBack(key K) (ok bool) {
+	var i uint32
+	if i, ok = m.index.Get(key); ok {
+		m.list.moveToBack(i)
+	}
+	return
+}
+
+// MoveToFront moves |key| to the front of the order,
+// returns false if |key| is absent.
+func (m *OrderedMap[K, V]) MoveToFront(key K) (ok bool) {
+	var i uint32
+	if i, ok = m.index.Get(key); ok {
+		m.list.moveToFront(i)
+	}
+	return
+}
+
+// Front returns the first element in the order, if any.
+func (m *OrderedMap[K, V]) Front() (key K, value V, ok bool) {
+	if i := m.list.head; i != nilEntry {
+		key, value, ok = m.list.entries[i].key, m.list.entries[i].value, true
+	}
+	return
+}
+
+// Back returns the last element in the order, if any.
+func (m *OrderedMap[K, V]) Back() (key K, value V, ok bool) {
+	if i := m.list.tail; i != nilEntry {
+		key, value, ok = m.list.entries[i].key, m.list.entries[i].value, true
+	}
+	return
+}
+
+// Iter iterates the elements of the OrderedMap front to back,
+// passing them to the callback. The callback may delete the
+// element it is passed.
+func (m *OrderedMap[K, V]) Iter(cb func(k K, v V) (stop bool)) {
+	for i := m.list.head; i != nilEntry; {
+		e := m.list.entries[i]
+		i = e.next
+		if stop := cb(e.key, e.value); stop {
+			return
+		}
+	}
+}
+
+// ReverseIter iterates the elements of the OrderedMap back to
+// front, passing them to the callback. The callback may delete
+// the element it is passed.
+func (m *OrderedMap[K, V]) ReverseIter(cb func(k K, v V) (stop bool)) {
+	for i := m.list.tail; i != nilEntry; {
+		e := m.list.entries[i]
+		i = e.prev
+		if stop := cb(e.key, e.value); stop {
+			return
+		}
+	}
+}
+
+// Clear removes all elements from the OrderedMap.
+func (m *OrderedMap[K, V]) Clear() {
+	m.index.Clear()
+	m.list.clear()
+}
+
+// Count returns the number of elements in the OrderedMap.
+func (m *OrderedMap[K, V]) Count() int {
+	return m.index.Count()
+}
@@ -0,0 +1,150 @@
+// Copyright 2023 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swiss
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOrderedMap(t *testing.T) {
+	t.Run("uint32=0", func(t *testing.T) {
+		testOrderedMap(t, genUint32Data(0))
+	})
+	t.Run("uint32=1000", func(t *testing.T) {
+		testOrderedMap(t, genUint32Data(1000))
+	})
+	t.Run("strings=10_000", func(t *testing.T) {
+		testOrderedMap(t, genStringData(16, 10_000))
+	})
+}
+
+func testOrderedMap[K comparable](t *testing.T, keys []K) {
+	t.Run("put", func(t *testing.T) {
+		m := NewOrderedMap[K, int](0)
+		for i, k := range keys {
+			m.Put(k, i)
+		}
+		// overwrites keep their position
+		for i, k := range keys {
+			m.Put(k, -i)
+		}
+		assert.Equal(t, len(keys), m.Count())
+		assertOrder(t, m, keys)
+		for i, k := range keys {
+			v, ok := m.Get(k)
+			assert.True(t, ok)
+			assert.Equal(t, -i, v)
+			assert.True(t, m.Has(k))
+		}
+	})
+	t.Run("delete", func(t *testing.T) {
+		m := NewOrderedMap[K, int](uint32(len(keys)))
+		for i, k := range keys {
+			m.Put(k, i)
+		}
+		var odd []K
+		for i, k := range keys {
+			if i%2 == 0 {
+				assert.True(t, m.Delete(k))
+				assert.False(t, m.Delete(k))
+			} else {
+				odd = append(odd, k)
+			}
+		}
+		assertOrder(t, m, odd)
+		// re-inserted keys go to the back, reusing freed entries
+		for i, k := range keys {
+			if i%2 == 0 {
+				m.Put(k, i)
+			}
+		}
+		assert.Equal(t, len(keys), len(m.list.entries))
+		for i, k := range keys {
+			if i%2 == 0 {
+				odd = append(odd, k)
+			}
+		}
+		assertOrder(t, m, odd)
+	})
+	t.Run("move", func(t *testing.T) {
+		if len(keys) < 2 {
+			return
+		}
+		m := NewOrderedMap[K, int](uint32(len(keys)))
+		for i, k := range keys {
+			m.Put(k, i)
+		}
+		assert.True(t, m.MoveToBack(keys[0]))
+		assert.True(t, m.MoveToFront(keys[len(keys)-1]))
+		exp := append([]K{keys[len(keys)-1]}, keys[1:len(keys)-1]...)
+		exp = append(exp, keys[0])
+		assertOrder(t, m, exp)
+		k, v, ok := m.Front()
+		assert.True(t, ok)
+		assert.Equal(t, keys[len(keys)-1], k)
+		assert.Equal(t, len(keys)-1, v)
+		k, v, ok = m.Back()
+		assert.True(t, ok)
+		assert.Equal(t, keys[0], k)
+		assert.Equal(t, 0, v)
+	})
+	t.Run("delete on iter", func(t *testing.T) {
+		m := NewOrderedMap[K, int](uint32(len(keys)))
+		for i, k := range keys {
+			m.Put(k, i)
+		}
+		m.Iter(func(k K, v int) (stop bool) {
+			m.Delete(k)
+			return
+		})
+		assert.Equal(t, 0, m.Count())
+		_, _, ok := m.Front()
+		assert.False(t, ok)
+		_, _, ok = m.Back()
+		assert.False(t, ok)
+	})
+	t.Run("clear", func(t *testing.T) {
+		m := NewOrderedMap[K, int](0)
+		for i, k := range keys {
+			m.Put(k, i)
+		}
+		m.Clear()
+		assert.Equal(t, 0, m.Count())
+		assertOrder(t, m, nil)
+		for _, k := range keys {
+			assert.False(t, m.Has(k))
+		}
+	})
+}
+
+func assertOrder[K comparable, V any](t *testing.T, m *OrderedMap[K, V], exp []K) {
+	var fwd, bwd []K
+	m.Iter(func(k K, _ V) (stop bool) {
+		fwd = append(fwd, k)
+		return
+	})
+	m.ReverseIter(func(k K, _ V) (stop bool) {
+		bwd = append(bwd, k)
+		return
+	})
+	assert.Equal(t, len(exp), len(fwd))
+	assert.Equal(t, len(exp), len(bwd))
+	for i := range exp {
+		assert.Equal(t, exp[i], fwd[i])
+		assert.Equal(t, exp[i], bwd[len(bwd)-1-i])
+	}
+}
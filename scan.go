@@ -0,0 +1,123 @@
+// Copyright 2023 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swiss
+
+import (
+	"math/bits"
+)
+
+const (
+	// scanGenShift is the offset of the seed generation in a
+	// cursor, below it is a 57 bit position in hash space
+	scanGenShift = 57
+	scanPosMask  = uint64(1)<<scanGenShift - 1
+	scanGenMask  = uint64(0x7f)
+)
+
+// Scan incrementally iterates the keys of the Map. A scan starts with
+// a |cursor| of zero and continues by passing the returned |next|
+// cursor to the following call, until |next| is zero. Each call
+// returns roughly |count| keys, possibly more or fewer.
+//
+// Unlike Iter, the Map may be mutated between calls to Scan. Every key
+// present in the Map for the duration of the scan is returned, even if
+// the Map grows or compacts in between. Keys inserted or deleted during
+// the scan may or may not be returned.
+//
+// Like Redis's SCAN, the cursor is a position in hash space rather than
+// a slot index. probeStart maps hash prefixes to groups monotonically,
+// so for any table size the keys homed in a group are exactly those
+// whose prefix falls in a contiguous range, and the cursor remains
+// meaningful across rehashes. If the Map is reseeded during the scan
+// the cursor is invalidated and the scan restarts, so keys may be
+// returned more than once.
+func (m *Map[K, V]) Scan(cursor uint64, count int) (next uint64, keys []K) {
+	m.guard.checkRead()
+	if count < 1 {
+		count = 1
+	}
+	// |count| may exceed the number of keys
+	sz := count
+	if n := m.Count(); sz > n {
+		sz = n
+	}
+	keys = make([]K, 0, sz)
+	// the high bits of the cursor hold the seed
+	// generation, the low bits the hash position
+	gen := uint64(m.seedGen) & scanGenMask
+	pos := h1(cursor & scanPosMask)
+	if cursor>>scanGenShift != gen {
+		pos = 0
+	}
+	for len(keys) < count {
+		g := probeStart(pos, m.ngroups)
+		keys = m.scanRange(keys, g, pos)
+		var ok bool
+		if pos, ok = groupEnd(g, uint64(m.ngroups)); !ok {
+			return 0, keys
+		}
+	}
+	next = gen<<scanGenShift | uint64(pos)
+	return
+}
+
+// scanRange appends the keys homed in group |home| whose
+// hash position is at least |lo|.
+func (m *Map[K, V]) scanRange(keys []K, home uint64, lo h1) []K {
+	g := home
+	for {
+		ctrl, grp := m.at(g)
+		for s, c := range ctrl {
+			if c == empty || c == tombstone {
+				continue
+			}
+			k := grp.keys[s]
+			hi, _ := splitHash(m.hash.hash(k))
+			if hi >= lo && probeStart(hi, m.ngroups) == home {
+				keys = append(keys, k)
+			}
+		}
+		// a key homed in |home| is never placed beyond
+		// the first group with an empty slot
+		if metaMatchEmpty(ctrl) != 0 {
+			return keys
+		}
+		g += 1 // linear probing
+		if g >= uint64(m.ngroups) {
+			g = 0
+		}
+	}
+}
+
+// groupEnd returns the first hash position homed beyond group |g|
+// of |n| groups, or false if |g| is the last group. Group |g| holds
+// the positions |p| for which floor((p<<7) * n / 2^64) == g.
+func groupEnd(g, n uint64) (end h1, ok bool) {
+	if g+1 >= n {
+		return 0, false
+	}
+	// first 64 bit hash in group g+1 is ceil((g+1) * 2^64 / n)
+	q, r := bits.Div64(g+1, 0, n)
+	if r != 0 {
+		q++
+	}
+	// round up to a multiple of 2^7, the low hash bits
+	// are not part of the position
+	end = h1(q >> 7)
+	if q&h2Mask != 0 {
+		end++
+	}
+	return end, end <= h1(scanPosMask)
+}
@@ -0,0 +1,146 @@
+// Copyright 2023 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swiss
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScan(t *testing.T) {
+	t.Run("uint32=0", func(t *testing.T) {
+		testScan(t, genUint32Data(0))
+	})
+	t.Run("uint32=1000", func(t *testing.T) {
+		testScan(t, genUint32Data(1000))
+	})
+	t.Run("strings=10_000", func(t *testing.T) {
+		testScan(t, genStringData(16, 10_000))
+	})
+	t.Run("uint32=100_000", func(t *testing.T) {
+		testScan(t, genUint32Data(100_000))
+	})
+}
+
+func testScan[K comparable](t *testing.T, keys []K) {
+	// |mutate| is called between each call to Scan
+	scan := func(m *Map[K, int], count int, mutate func()) map[K]int {
+		seen := make(map[K]int, m.Count())
+		var cursor uint64
+		for {
+			next, batch := m.Scan(cursor, count)
+			for _, k := range batch {
+				seen[k]++
+			}
+			if next == 0 {
+				return seen
+			}
+			assert.Greater(t, next, cursor)
+			cursor = next
+			mutate()
+		}
+	}
+	t.Run("complete", func(t *testing.T) {
+		m := NewMap[K, int](uint32(len(keys)))
+		for i, k := range keys {
+			m.Put(k, i)
+		}
+		for _, count := range []int{0, 1, 10, 1000, math.MaxInt} {
+			seen := scan(m, count, func() {})
+			assert.Equal(t, len(keys), len(seen))
+			for _, k := range keys {
+				assert.Equal(t, 1, seen[k])
+			}
+		}
+	})
+	t.Run("grow", func(t *testing.T) {
+		// the first half of |keys| is present throughout,
+		// the second half is inserted mid-scan
+		half := len(keys) / 2
+		m := NewMap[K, int](0)
+		for i, k := range keys[:half] {
+			m.Put(k, i)
+		}
+		groups := m.ngroups
+		rest := keys[half:]
+		seen := scan(m, 10, func() {
+			for i := 0; i < 100 && len(rest) > 0; i++ {
+				m.Put(rest[0], 0)
+				rest = rest[1:]
+			}
+		})
+		if len(keys) > 0 {
+			assert.Greater(t, m.ngroups, groups)
+		}
+		for _, k := range keys[:half] {
+			assert.Equal(t, 1, seen[k])
+		}
+	})
+	t.Run("delete", func(t *testing.T) {
+		// the odd keys are present throughout,
+		// the even keys are deleted mid-scan
+		m := NewMap[K, int](uint32(len(keys)))
+		for i, k := range keys {
+			m.Put(k, i)
+		}
+		var i int
+		seen := scan(m, 10, func() {
+			for j := 0; j < 100 && i < len(keys); j++ {
+				m.Delete(keys[i])
+				i += 2
+			}
+		})
+		for i := 1; i < len(keys); i += 2 {
+			assert.Equal(t, 1, seen[keys[i]])
+		}
+	})
+	t.Run("reseed", func(t *testing.T) {
+		m := NewMap[K, int](uint32(len(keys)))
+		for i, k := range keys {
+			m.Put(k, i)
+		}
+		var reseeded bool
+		seen := scan(m, 10, func() {
+			if !reseeded {
+				m.reseed()
+				reseeded = true
+			}
+		})
+		for _, k := range keys {
+			assert.GreaterOrEqual(t, seen[k], 1)
+		}
+	})
+}
+
+func TestGroupEnd(t *testing.T) {
+	for _, n := range []uint64{1, 3, 128, 1<<32 - 1, 1<<32 + 7, 1<<40 + 3} {
+		for _, g := range []uint64{0, 1, n / 2, n - 2, n - 1} {
+			if g >= n {
+				continue
+			}
+			end, ok := groupEnd(g, n)
+			if g == n-1 {
+				assert.False(t, ok)
+				continue
+			}
+			assert.True(t, ok)
+			// |end| is the first position past group |g|
+			assert.Equal(t, g, probeStart(end-1, int(n)))
+			assert.Equal(t, g+1, probeStart(end, int(n)))
+		}
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright 2023 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swiss
+
+// Set is a hash set of keys backed by a Map.
+type Set[K comparable] struct {
+	m *Map[K, struct{}]
+}
+
+// NewSet constructs a Set.
+func NewSet[K comparable](sz uint32) *Set[K] {
+	return &Set[K]{m: NewMap[K, struct{}](sz)}
+}
+
+// Add inserts |key| into |s|.
+func (s *Set[K]) Add(key K) {
+	s.m.Put(key, struct{}{})
+}
+
+// Has returns true if |key| is present in |s|.
+func (s *Set[K]) Has(key K) bool {
+	return s.m.Has(key)
+}
+
+// Delete attempts to remove |key|, returns true successful.
+func (s *Set[K]) Delete(key K) bool {
+	return s.m.Delete(key)
+}
+
+// Iter iterates the elements of the Set, passing them to the callback.
+// It makes the same guarantees as Map.Iter.
+func (s *Set[K]) Iter(cb func(k K) (stop bool)) {
+	s.m.Iter(func(k K, _ struct{}) bool {
+		return cb(k)
+	})
+}
+
+// Clear removes all elements from the Set.
+func (s *Set[K]) Clear() {
+	s.m.Clear()
+}
+
+// Count returns the number of elements in the Set.
+func (s *Set[K]) Count() int {
+	return s.m.Count()
+}
+
+// Capacity returns the number of additional elements
+// the can be added to the Set before resizing.
+func (s *Set[K]) Capacity() int {
+	return s.m.Capacity()
+}
+
+// Union adds every element of |other| to |s|.
+func (s *Set[K]) Union(other *Set[K]) {
+	s.m.Merge(other.m, func(_ K, a, _ struct{}) struct{} {
+		return a
+	})
+}
+
+// IntersectWith removes every element of |s| not present in |other|.
+func (s *Set[K]) IntersectWith(other *Set[K]) {
+	s.m.IntersectWith(other.m)
+}
+
+// Subtract removes every element of |s| present in |other|.
+func (s *Set[K]) Subtract(other *Set[K]) {
+	s.m.Subtract(other.m)
+}
@@ -0,0 +1,79 @@
+// Copyright 2023 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swiss
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSet(t *testing.T) {
+	t.Run("uint32=1000", func(t *testing.T) {
+		testSet(t, genUint32Data(1000))
+	})
+	t.Run("strings=10_000", func(t *testing.T) {
+		testSet(t, genStringData(16, 10_000))
+	})
+}
+
+func testSet[K comparable](t *testing.T, keys []K) {
+	lo, hi := len(keys)/3, 2*len(keys)/3
+	mk := func(keys []K) *Set[K] {
+		s := NewSet[K](0)
+		for _, k := range keys {
+			s.Add(k)
+		}
+		return s
+	}
+	check := func(s *Set[K], from, to int) {
+		assert.Equal(t, to-from, s.Count())
+		for i, k := range keys {
+			assert.Equal(t, i >= from && i < to, s.Has(k))
+		}
+		var n int
+		s.Iter(func(k K) (stop bool) {
+			n++
+			return
+		})
+		assert.Equal(t, to-from, n)
+	}
+	t.Run("union", func(t *testing.T) {
+		s := mk(keys[:hi])
+		s.Union(mk(keys[lo:]))
+		check(s, 0, len(keys))
+	})
+	t.Run("intersect", func(t *testing.T) {
+		s := mk(keys[:hi])
+		s.IntersectWith(mk(keys[lo:]))
+		check(s, lo, hi)
+	})
+	t.Run("subtract", func(t *testing.T) {
+		s := mk(keys)
+		s.Subtract(mk(keys[hi:]))
+		check(s, 0, hi)
+		s.Subtract(mk(keys[:lo]))
+		check(s, lo, hi)
+	})
+	t.Run("delete", func(t *testing.T) {
+		s := mk(keys)
+		for _, k := range keys[lo:] {
+			assert.True(t, s.Delete(k))
+		}
+		check(s, 0, lo)
+		s.Clear()
+		check(s, 0, 0)
+	})
+}
@@ -0,0 +1,109 @@
+// Copyright 2023 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swiss
+
+import (
+	"math/bits"
+)
+
+const (
+	sketchDepth = 4
+	// counters are 4 bits, 16 to a word
+	counterMax   = 15
+	counterWidth = 4
+	resetMask    = 0x7777_7777_7777_7777
+)
+
+// sketchSeeds are odd constants used to derive
+// one counter index per row from a single hash.
+var sketchSeeds = [sketchDepth]uint64{
+	0xc3a5c85c97cb3127,
+	0xb492b66fbe98f273,
+	0x9ae16a3b2f90404f,
+	0xcbf29ce484222325,
+}
+
+// sketch is a count-min sketch of 4-bit counters used to
+// estimate access frequency. Counters are periodically
+// halved so that the sketch favors recent history.
+type sketch struct {
+	rows    [sketchDepth][]uint64
+	mask    uint64
+	adds    uint64
+	resetAt uint64
+}
+
+func newSketch(n uint32) (s *sketch) {
+	if n < 16 {
+		n = 16
+	}
+	// one counter per expected element per row
+	width := uint64(1) << (64 - bits.LeadingZeros64(uint64(n)-1))
+	s = &sketch{
+		mask:    width - 1,
+		resetAt: uint64(n) * 10,
+	}
+	for i := range s.rows {
+		s.rows[i] = make([]uint64, width/16)
+	}
+	return
+}
+
+func (s *sketch) index(h uint64, row int) (word, shift uint64) {
+	x := h * sketchSeeds[row]
+	x ^= x >> 32
+	i := x & s.mask
+	return i >> 4, (i & 15) * counterWidth
+}
+
+// increment records an access of the element hashed to |h|.
+func (s *sketch) increment(h uint64) {
+	var added bool
+	for r := range s.rows {
+		w, sh := s.index(h, r)
+		if (s.rows[r][w]>>sh)&counterMax < counterMax {
+			s.rows[r][w] += 1 << sh
+			added = true
+		}
+	}
+	if added {
+		s.adds++
+		if s.adds >= s.resetAt {
+			s.reset()
+		}
+	}
+}
+
+// estimate returns the estimated access frequency of the element hashed to |h|.
+func (s *sketch) estimate(h uint64) (f uint8) {
+	f = counterMax
+	for r := range s.rows {
+		w, sh := s.index(h, r)
+		if c := uint8((s.rows[r][w] >> sh) & counterMax); c < f {
+			f = c
+		}
+	}
+	return
+}
+
+// reset halves every counter.
+func (s *sketch) reset() {
+	for r := range s.rows {
+		for i := range s.rows[r] {
+			s.rows[r][i] = (s.rows[r][i] >> 1) & resetMask
+		}
+	}
+	s.adds /= 2
+}
@@ -0,0 +1,253 @@
+// Copyright 2023 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swiss
+
+import (
+	"github.com/dolthub/maphash"
+)
+
+// TinyLFU is a cost-bounded cache using the W-TinyLFU policy.
+// New elements enter a small LRU window; elements evicted from the
+// window compete for a place in the main segmented LRU, and are
+// only admitted if a frequency sketch estimates they are accessed
+// more often than the element they would displace. This keeps the
+// cache from being flushed by scans of one-off keys.
+type TinyLFU[K comparable, V any] struct {
+	index *Map[K, lfuRef]
+	// window, probation and protected segments
+	segs    [3]entryList[K, lfuEntry[V]]
+	costs   [3]int64
+	limits  [3]int64
+	freq    *sketch
+	hash    maphash.Hasher[K]
+	onEvict func(k K, v V)
+	hits    uint64
+	misses  uint64
+}
+
+const (
+	window uint8 = iota
+	probation
+	protected
+)
+
+type lfuRef struct {
+	seg uint8
+	idx uint32
+}
+
+type lfuEntry[V any] struct {
+	value V
+	cost  int64
+}
+
+// NewTinyLFU constructs a TinyLFU holding elements with a total
+// cost of at most |maxCost|. |sz| is the expected number of
+// resident elements and sizes the index and frequency sketch.
+// If |onEvict| is non-nil, it is called with each element evicted
+// or rejected by the admission policy.
+func NewTinyLFU[K comparable, V any](maxCost int64, sz uint32, onEvict func(k K, v V)) *TinyLFU[K, V] {
+	if maxCost <= 0 {
+		panic("swiss: TinyLFU cost must be positive")
+	}
+	// 1% window, the remainder split 20/80
+	// between probation and protected
+	win := maxCost / 100
+	if win < 1 {
+		win = 1
+	}
+	main := maxCost - win
+	c := &TinyLFU[K, V]{
+		index:   NewMap[K, lfuRef](sz),
+		limits:  [3]int64{win, main - main*8/10, main * 8 / 10},
+		freq:    newSketch(sz),
+		hash:    maphash.NewHasher[K](),
+		onEvict: onEvict,
+	}
+	for i := range c.segs {
+		c.segs[i] = newEntryList[K, lfuEntry[V]](0)
+	}
+	return c
+}
+
+// Get returns the |value| mapped by |key| if one exists,
+// recording the access.
+func (c *TinyLFU[K, V]) Get(key K) (value V, ok bool) {
+	c.freq.increment(c.hash.Hash(key))
+	var ref lfuRef
+	if ref, ok = c.index.Get(key); !ok {
+		c.misses++
+		return
+	}
+	c.hits++
+	value = c.segs[ref.seg].entries[ref.idx].value.value
+	c.touch(key, ref)
+	return
+}
+
+// Peek returns the |value| mapped by |key| if one exists,
+// without recording the access.
+func (c *TinyLFU[K, V]) Peek(key K) (value V, ok bool) {
+	var ref lfuRef
+	if ref, ok = c.index.Get(key); ok {
+		value = c.segs[ref.seg].entries[ref.idx].value.value
+	}
+	return
+}
+
+// Has returns true if |key| is present in |c|, without recording the access.
+func (c *TinyLFU[K, V]) Has(key K) bool {
+	return c.index.Has(key)
+}
+
+// Put inserts or updates |key| with |value| and |cost|, recording
+// the access. Returns false if |cost| exceeds the capacity of |c|,
+// in which case |key| is not resident after Put.
+func (c *TinyLFU[K, V]) Put(key K, value V, cost int64) (ok bool) {
+	c.freq.increment(c.hash.Hash(key))
+	if ref, found := c.index.Get(key); found {
+		e := &c.segs[ref.seg].entries[ref.idx].value
+		c.costs[ref.seg] += cost - e.cost
+		e.value, e.cost = value, cost
+		c.touch(key, ref)
+	} else {
+		i := c.segs[window].pushBack(key, lfuEntry[V]{value: value, cost: cost})
+		c.index.Put(key, lfuRef{seg: window, idx: i})
+		c.costs[window] += cost
+	}
+	if cost > c.limits[window]+c.limits[probation]+c.limits[protected] {
+		c.Remove(key)
+		return false
+	}
+	c.evict()
+	return c.index.Has(key)
+}
+
+// Remove attempts to remove |key|, returns true successful.
+func (c *TinyLFU[K, V]) Remove(key K) (ok bool) {
+	var ref lfuRef
+	if ref, ok = c.index.Get(key); ok {
+		c.index.Delete(key)
+		c.costs[ref.seg] -= c.segs[ref.seg].entries[ref.idx].value.cost
+		c.segs[ref.seg].remove(ref.idx)
+	}
+	return
+}
+
+// touch updates the position of |key| after an access.
+func (c *TinyLFU[K, V]) touch(key K, ref lfuRef) {
+	switch ref.seg {
+	case window, protected:
+		c.segs[ref.seg].moveToBack(ref.idx)
+	case probation:
+		// promote to protected, demoting protected's
+		// least recently used elements if necessary
+		c.move(key, ref, protected)
+		for c.costs[protected] > c.limits[protected] {
+			i := c.segs[protected].head
+			k := c.segs[protected].entries[i].key
+			c.move(k, lfuRef{seg: protected, idx: i}, probation)
+		}
+	}
+}
+
+// move transfers |key| to the back of segment |seg|.
+func (c *TinyLFU[K, V]) move(key K, ref lfuRef, seg uint8) {
+	e := c.segs[ref.seg].entries[ref.idx].value
+	c.segs[ref.seg].remove(ref.idx)
+	c.costs[ref.seg] -= e.cost
+	i := c.segs[seg].pushBack(key, e)
+	c.costs[seg] += e.cost
+	c.index.Put(key, lfuRef{seg: seg, idx: i})
+}
+
+// evict moves overflow from the window into the main segments,
+// where each candidate must win against the main segments'
+// least recently used elements to be admitted.
+func (c *TinyLFU[K, V]) evict() {
+	for c.costs[window] > c.limits[window] {
+		i := c.segs[window].head
+		cand := c.segs[window].entries[i].key
+		c.move(cand, lfuRef{seg: window, idx: i}, probation)
+		c.admit(cand)
+	}
+	// updates may have grown the cost of resident elements
+	for c.costs[probation]+c.costs[protected] > c.limits[probation]+c.limits[protected] {
+		seg := probation
+		if c.segs[probation].head == nilEntry {
+			seg = protected
+		}
+		c.evictKey(c.segs[seg].entries[c.segs[seg].head].key)
+	}
+}
+
+// admit evicts elements from the main segments until
+// they fit within their limit, |cand| or its victims.
+func (c *TinyLFU[K, V]) admit(cand K) {
+	limit := c.limits[probation] + c.limits[protected]
+	candFreq := c.freq.estimate(c.hash.Hash(cand))
+	for c.costs[probation]+c.costs[protected] > limit {
+		seg := probation
+		if c.segs[probation].head == c.segs[probation].tail {
+			// |cand| is the only element in probation
+			seg = protected
+		}
+		i := c.segs[seg].head
+		if i == nilEntry {
+			c.evictKey(cand)
+			return
+		}
+		victim := c.segs[seg].entries[i].key
+		if victim == cand {
+			c.evictKey(cand)
+			return
+		}
+		if candFreq > c.freq.estimate(c.hash.Hash(victim)) {
+			c.evictKey(victim)
+		} else {
+			c.evictKey(cand)
+			return
+		}
+	}
+}
+
+func (c *TinyLFU[K, V]) evictKey(key K) {
+	ref, _ := c.index.Get(key)
+	v := c.segs[ref.seg].entries[ref.idx].value.value
+	c.Remove(key)
+	if c.onEvict != nil {
+		c.onEvict(key, v)
+	}
+}
+
+// Count returns the number of elements in the TinyLFU.
+func (c *TinyLFU[K, V]) Count() int {
+	return c.index.Count()
+}
+
+// Cost returns the total cost of the elements in the TinyLFU.
+func (c *TinyLFU[K, V]) Cost() int64 {
+	return c.costs[window] + c.costs[probation] + c.costs[protected]
+}
+
+// Hits returns the number of Get calls that found their key.
+func (c *TinyLFU[K, V]) Hits() uint64 {
+	return c.hits
+}
+
+// Misses returns the number of Get calls that did not find their key.
+func (c *TinyLFU[K, V]) Misses() uint64 {
+	return c.misses
+}
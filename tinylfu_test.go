@@ -0,0 +1,192 @@
+// Copyright 2023 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swiss
+
+import (
+	"math/rand"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTinyLFU(t *testing.T) {
+	t.Run("put get", func(t *testing.T) {
+		keys := genUint32Data(1000)
+		c := NewTinyLFU[uint32, int](int64(len(keys)), uint32(len(keys)), nil)
+		for i, k := range keys {
+			assert.True(t, c.Put(k, i, 1))
+		}
+		assert.Equal(t, len(keys), c.Count())
+		for i, k := range keys {
+			v, ok := c.Get(k)
+			assert.True(t, ok)
+			assert.Equal(t, i, v)
+		}
+		assert.Equal(t, uint64(len(keys)), c.Hits())
+		assert.True(t, c.Remove(keys[0]))
+		assert.False(t, c.Has(keys[0]))
+		_, ok := c.Get(keys[0])
+		assert.False(t, ok)
+		assert.Equal(t, uint64(1), c.Misses())
+		assert.Equal(t, int64(len(keys)-1), c.Cost())
+	})
+	t.Run("cost bound", func(t *testing.T) {
+		const maxCost = 10_000
+		var evicted int
+		c := NewTinyLFU[int, int](maxCost, 1000, func(k, v int) {
+			evicted++
+		})
+		assert.False(t, c.Put(-1, -1, maxCost+1))
+		for i := 0; i < 100_000; i++ {
+			k := rand.Intn(5000)
+			if _, ok := c.Get(k); !ok {
+				c.Put(k, k, int64(rand.Intn(20)+1))
+			}
+			assert.LessOrEqual(t, c.Cost(), int64(maxCost))
+		}
+		var sum int64
+		for s := range c.segs {
+			for i := c.segs[s].head; i != nilEntry; i = c.segs[s].entries[i].next {
+				sum += c.segs[s].entries[i].value.cost
+			}
+		}
+		assert.Equal(t, c.Cost(), sum)
+		assert.Greater(t, evicted, 0)
+	})
+	t.Run("scan resistance", func(t *testing.T) {
+		const capacity = 1000
+		lru := NewLRU[uint64, uint64](capacity, nil)
+		lfu := NewTinyLFU[uint64, uint64](capacity, capacity, nil)
+		trace := scanTrace(200_000, capacity)
+		lruRatio := replayTrace(trace, func(k uint64) bool {
+			if _, ok := lru.Get(k); ok {
+				return true
+			}
+			lru.Put(k, k)
+			return false
+		})
+		lfuRatio := replayTrace(trace, func(k uint64) bool {
+			if _, ok := lfu.Get(k); ok {
+				return true
+			}
+			lfu.Put(k, k, 1)
+			return false
+		})
+		t.Logf("hit ratio: lru=%.3f tinylfu=%.3f", lruRatio, lfuRatio)
+		assert.Greater(t, lfuRatio, lruRatio)
+	})
+}
+
+func TestSketch(t *testing.T) {
+	s := newSketch(1024)
+	for i := 0; i < 10; i++ {
+		s.increment(42)
+	}
+	assert.Equal(t, uint8(10), s.estimate(42))
+	for i := 0; i < 10; i++ {
+		s.increment(42)
+	}
+	// saturates
+	assert.Equal(t, uint8(counterMax), s.estimate(42))
+	s.reset()
+	assert.Equal(t, uint8(counterMax/2), s.estimate(42))
+}
+
+// zipfTrace returns |n| keys drawn from a Zipfian distribution.
+func zipfTrace(n int, s float64, keys uint64) (trace []uint64) {
+	z := rand.NewZipf(rand.New(rand.NewSource(int64(n))), s, 1, keys-1)
+	trace = make([]uint64, n)
+	for i := range trace {
+		trace[i] = z.Uint64()
+	}
+	return
+}
+
+// scanTrace interleaves a Zipfian trace with
+// long scans of keys that are never reused.
+func scanTrace(n, capacity int) (trace []uint64) {
+	zipf := zipfTrace(n, 1.1, uint64(capacity*100))
+	scan := uint64(1 << 40)
+	for i := 0; i < len(zipf); i += capacity {
+		end := i + capacity
+		if end > len(zipf) {
+			end = len(zipf)
+		}
+		trace = append(trace, zipf[i:end]...)
+		for j := 0; j < capacity; j++ {
+			trace = append(trace, scan)
+			scan++
+		}
+	}
+	return
+}
+
+func replayTrace(trace []uint64, access func(k uint64) (hit bool)) float64 {
+	var hits int
+	for _, k := range trace {
+		if access(k) {
+			hits++
+		}
+	}
+	return float64(hits) / float64(len(trace))
+}
+
+// BenchmarkCacheHitRatio replays synthetic traces against LRU and
+// TinyLFU and reports their hit ratios alongside their throughput.
+func BenchmarkCacheHitRatio(b *testing.B) {
+	const n = 1 << 20
+	traces := []struct {
+		name  string
+		trace []uint64
+	}{
+		{name: "zipf=1.0", trace: zipfTrace(n, 1.0001, n)},
+		{name: "zipf=1.2", trace: zipfTrace(n, 1.2, n)},
+		{name: "zipf+scan", trace: scanTrace(n, 1<<12)},
+	}
+	for _, tr := range traces {
+		for _, capacity := range []int{1 << 10, 1 << 14} {
+			name := tr.name + "/capacity=" + strconv.Itoa(capacity)
+			b.Run(name+"/lru", func(b *testing.B) {
+				var ratio float64
+				for i := 0; i < b.N; i++ {
+					c := NewLRU[uint64, uint64](uint32(capacity), nil)
+					ratio = replayTrace(tr.trace, func(k uint64) bool {
+						if _, ok := c.Get(k); ok {
+							return true
+						}
+						c.Put(k, k)
+						return false
+					})
+				}
+				b.ReportMetric(ratio*100, "hit%")
+			})
+			b.Run(name+"/tinylfu", func(b *testing.B) {
+				var ratio float64
+				for i := 0; i < b.N; i++ {
+					c := NewTinyLFU[uint64, uint64](int64(capacity), uint32(capacity), nil)
+					ratio = replayTrace(tr.trace, func(k uint64) bool {
+						if _, ok := c.Get(k); ok {
+							return true
+						}
+						c.Put(k, k, 1)
+						return false
+					})
+				}
+				b.ReportMetric(ratio*100, "hit%")
+			})
+		}
+	}
+}
@@ -0,0 +1,138 @@
+// Copyright 2023 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swiss
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+// Validate walks the table and checks the Map's invariants, returning
+// an error describing the first violation found. It is intended for
+// debugging and testing, and is O(capacity).
+func (m *Map[K, V]) Validate() error {
+	if m.ngroups == 0 {
+		return fmt.Errorf("swiss: empty table")
+	}
+	if err := m.table.validate(); err != nil {
+		return err
+	}
+	if exp := uint64(m.ngroups) * m.maxLoad; m.limit != exp {
+		return fmt.Errorf("swiss: limit is %d, expected %d for %d groups", m.limit, exp, m.ngroups)
+	}
+	if m.resident > m.limit {
+		return fmt.Errorf("swiss: resident count %d exceeds limit %d", m.resident, m.limit)
+	}
+	var full, dead, free uint64
+	var zero K
+	blank := newEmptyMetadata()
+	for g := uint64(0); g < uint64(m.ngroups); g++ {
+		ctrl, grp := m.at(g)
+		if *ctrl != blank && !m.table.isDirty(g) {
+			return fmt.Errorf("swiss: group %d is in use but not marked dirty", g)
+		}
+		for s, c := range ctrl {
+			k := grp.keys[s]
+			switch {
+			case c == empty || c == tombstone:
+				if c == tombstone {
+					dead++
+				} else {
+					free++
+				}
+				if k != zero {
+					return fmt.Errorf("swiss: unoccupied slot (%d, %d) holds key %v", g, s, k)
+				}
+				continue
+			case c < 0:
+				return fmt.Errorf("swiss: invalid control byte %#x at (%d, %d)", uint8(c), g, s)
+			}
+			full++
+			if _, lo := splitHash(m.hash.hash(k)); h2(c) != lo {
+				return fmt.Errorf("swiss: control byte %#x at (%d, %d) does not match h2 %#x of key %v",
+					uint8(c), g, s, uint8(lo), k)
+			}
+		}
+	}
+	if dead != m.dead {
+		return fmt.Errorf("swiss: dead count is %d, found %d tombstones", m.dead, dead)
+	}
+	if full+dead != m.resident {
+		return fmt.Errorf("swiss: resident count is %d, found %d elements and %d tombstones",
+			m.resident, full, dead)
+	}
+	// probe sequences end at an empty slot, without
+	// one find would not terminate for absent keys
+	if free == 0 {
+		return fmt.Errorf("swiss: table has no empty slots")
+	}
+	for g := uint64(0); g < uint64(m.ngroups); g++ {
+		ctrl, grp := m.at(g)
+		for s, c := range ctrl {
+			if c == empty || c == tombstone {
+				continue
+			}
+			k := grp.keys[s]
+			hi, lo := splitHash(m.hash.hash(k))
+			fg, fs, ok := m.find(k, hi, lo)
+			if !ok || fg != g || fs != uint32(s) {
+				return fmt.Errorf("swiss: key %v at (%d, %d) is not reachable from group %d",
+					k, g, s, probeStart(hi, m.ngroups))
+			}
+		}
+	}
+	return nil
+}
+
+// validate checks the layout of the table.
+func (t *table[K, V]) validate() error {
+	if t.chunks == nil {
+		if len(t.ctrl) != t.ngroups || len(t.groups) != t.ngroups {
+			return fmt.Errorf("swiss: %d control groups and %d groups, expected %d",
+				len(t.ctrl), len(t.groups), t.ngroups)
+		}
+		if exp := (t.ngroups + 63) / 64; len(t.dirty) != exp {
+			return fmt.Errorf("swiss: %d dirty words for %d groups, expected %d", len(t.dirty), t.ngroups, exp)
+		}
+		return nil
+	}
+	if t.ctrl != nil || t.groups != nil || t.dirty != nil {
+		return fmt.Errorf("swiss: split table also holds groups or dirty bits")
+	}
+	chunks := (t.ngroups + tableChunkMask) >> tableChunkShift
+	if len(t.chunks) != chunks {
+		return fmt.Errorf("swiss: %d chunks for %d groups, expected %d", len(t.chunks), t.ngroups, chunks)
+	}
+	for i, c := range t.chunks {
+		exp := t.ngroups - i<<tableChunkShift
+		if exp > tableChunkGroups {
+			exp = tableChunkGroups
+		}
+		if len(c.ctrl) != exp || len(c.groups) != exp {
+			return fmt.Errorf("swiss: chunk %d has %d control groups and %d groups, expected %d",
+				i, len(c.ctrl), len(c.groups), exp)
+		}
+		if refs := atomic.LoadInt32(&c.refs); refs < 1 {
+			return fmt.Errorf("swiss: chunk %d has %d references", i, refs)
+		}
+	}
+	return nil
+}
+
+// isDirty returns true if group |g| is marked dirty. Split
+// tables are not cleared in place, and so do not track writes.
+func (t *table[K, V]) isDirty(g uint64) bool {
+	return t.chunks != nil || t.dirty[g>>6]&(1<<(g&63)) != 0
+}
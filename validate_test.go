@@ -0,0 +1,148 @@
+// Copyright 2023 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swiss
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	keys := genUint32Data(1000)
+	setup := func() *Map[uint32, int] {
+		m := NewMap[uint32, int](0)
+		for i, k := range keys {
+			m.Put(k, i)
+		}
+		for _, k := range keys[:500] {
+			m.Delete(k)
+		}
+		require.NoError(t, m.Validate())
+		return m
+	}
+	// locate returns the slot holding |key|
+	locate := func(m *Map[uint32, int], key uint32) (g uint64, s uint32) {
+		hi, lo := splitHash(m.hash.hash(key))
+		g, s, ok := m.find(key, hi, lo)
+		require.True(t, ok)
+		return
+	}
+	t.Run("control byte", func(t *testing.T) {
+		m := setup()
+		g, s := locate(m, keys[999])
+		ctrl, _ := m.mut(g)
+		ctrl[s] ^= 1
+		assert.ErrorContains(t, m.Validate(), "does not match h2")
+	})
+	t.Run("invalid control byte", func(t *testing.T) {
+		m := setup()
+		g, s := locate(m, keys[999])
+		ctrl, _ := m.mut(g)
+		ctrl[s] = -3
+		assert.ErrorContains(t, m.Validate(), "invalid control byte")
+	})
+	t.Run("unreachable", func(t *testing.T) {
+		m := setup()
+		// find a key whose group terminates probe sequences
+		var g uint64
+		var s uint32
+		for _, k := range keys[500:] {
+			g, s = locate(m, k)
+			if ctrl, _ := m.at(g); metaMatchEmpty(ctrl) != 0 {
+				break
+			}
+		}
+		ctrl, grp := m.at(g)
+		require.NotZero(t, metaMatchEmpty(ctrl))
+		// move the key to the next group with an empty slot
+		k, v := grp.keys[s], grp.values[s]
+		m.deleteAt(g, s)
+		n := (g + 1) % uint64(m.ngroups)
+		for ctrl, _ = m.at(n); metaMatchEmpty(ctrl) == 0; ctrl, _ = m.at(n) {
+			n = (n + 1) % uint64(m.ngroups)
+		}
+		matches := metaMatchEmpty(ctrl)
+		s = nextMatch(&matches)
+		_, lo := splitHash(m.hash.hash(k))
+		ctrl, grp = m.mut(n)
+		grp.keys[s], grp.values[s] = k, v
+		ctrl[s] = int8(lo)
+		m.resident++
+		assert.ErrorContains(t, m.Validate(), "not reachable")
+	})
+	t.Run("counts", func(t *testing.T) {
+		m := setup()
+		m.resident++
+		assert.ErrorContains(t, m.Validate(), "resident count")
+		m = setup()
+		m.dead++
+		assert.ErrorContains(t, m.Validate(), "dead count")
+		m = setup()
+		m.limit--
+		assert.ErrorContains(t, m.Validate(), "limit")
+	})
+	t.Run("stale key", func(t *testing.T) {
+		m := setup()
+		g, s := locate(m, keys[999])
+		m.deleteAt(g, s)
+		_, grp := m.mut(g)
+		grp.keys[s] = keys[999]
+		assert.ErrorContains(t, m.Validate(), "unoccupied slot")
+	})
+	t.Run("no empty slots", func(t *testing.T) {
+		m := setup()
+		// replace every empty slot with a tombstone,
+		// keeping the counts and limit consistent
+		m.maxLoad = groupSize
+		m.limit = uint64(m.ngroups) * groupSize
+		for g := uint64(0); g < uint64(m.ngroups); g++ {
+			ctrl, _ := m.mut(g)
+			for s := range ctrl {
+				if ctrl[s] == empty {
+					ctrl[s] = tombstone
+					m.resident++
+					m.dead++
+				}
+			}
+		}
+		assert.ErrorContains(t, m.Validate(), "no empty slots")
+	})
+	t.Run("dirty", func(t *testing.T) {
+		m := setup()
+		g, _ := locate(m, keys[999])
+		m.dirty[g>>6] = 0
+		assert.ErrorContains(t, m.Validate(), "not marked dirty")
+		m = setup()
+		m.dirty = m.dirty[1:]
+		assert.ErrorContains(t, m.Validate(), "dirty words")
+	})
+	t.Run("chunks", func(t *testing.T) {
+		m := setup()
+		m.Fork()
+		assert.NoError(t, m.Validate())
+		m.chunks[0].refs = 0
+		assert.ErrorContains(t, m.Validate(), "references")
+		m = setup()
+		m.Fork()
+		m.chunks[0].ctrl = m.chunks[0].ctrl[1:]
+		assert.ErrorContains(t, m.Validate(), "chunk 0")
+		m = setup()
+		m.Fork()
+		m.dirty = []uint64{0}
+		assert.ErrorContains(t, m.Validate(), "split table")
+	})
+}